@@ -15,6 +15,12 @@ type Request interface {
 	Validate() error
 }
 
+// Sanitizer is implemented by requests that normalise their fields, e.g.
+// trimming or upper-casing them, which must happen before they are validated
+type Sanitizer interface {
+	Sanitize()
+}
+
 // bindData is helper function, returns false if data is not bound
 func BindData(c *gin.Context, req Request) bool {
 	// Bind incoming json to struct and check for validation errors
@@ -27,6 +33,10 @@ func BindData(c *gin.Context, req Request) bool {
 
 	log.Printf("Successfully deserialized json data: %v\n", req)
 
+	if sanitizer, ok := req.(Sanitizer); ok {
+		sanitizer.Sanitize()
+	}
+
 	if err := req.Validate(); err != nil {
 		errors := strings.Split(err.Error(), ";")
 		fErrors := make([]apperrors.FieldError, 0)
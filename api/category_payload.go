@@ -1,5 +1,69 @@
-package api 
+package api
 
+import (
+	"strings"
+	"swap/models"
 
+	validation "github.com/go-ozzo/ozzo-validation"
+)
 
 
+type AttributeSchemaPayload struct {
+	Schema		models.AttributeSchema	`json:"schema"`
+}
+
+
+func (r AttributeSchemaPayload) Validate() error {
+	return r.Schema.Check()
+}
+
+
+type RenameCategoryPayload struct {
+	Name		string	`json:"name"`
+	NewName		string	`json:"newName"`
+	DryRun		bool	`json:"dryRun"`
+}
+
+
+func (r *RenameCategoryPayload) Sanitize() {
+	r.Name = strings.TrimSpace(r.Name)
+	r.NewName = strings.ToUpper(strings.TrimSpace(r.NewName))
+}
+
+
+func (r RenameCategoryPayload) Validate() error {
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.Name, validation.Required),
+		validation.Field(&r.NewName, validation.Required, validation.Length(2, 50)),
+	)
+}
+
+
+type MergeCategoryPayload struct {
+	Source		string	`json:"source"`
+	Target		string	`json:"target"`
+	DryRun		bool	`json:"dryRun"`
+}
+
+
+func (r MergeCategoryPayload) Validate() error {
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.Source, validation.Required),
+		validation.Field(&r.Target, validation.Required),
+	)
+}
+
+
+type ReassignItemsPayload struct {
+	ItemIds		[]uint	`json:"itemIds"`
+	Target		string	`json:"target"`
+	DryRun		bool	`json:"dryRun"`
+}
+
+
+func (r ReassignItemsPayload) Validate() error {
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.ItemIds, validation.Required),
+		validation.Field(&r.Target, validation.Required),
+	)
+}
@@ -0,0 +1,25 @@
+package api
+
+import (
+	"strings"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+)
+
+
+// ClaimPayload is the optional note a claimant leaves for the owner of a give-away
+type ClaimPayload struct {
+	Message  string  `json:"message"`
+}
+
+
+func (r *ClaimPayload) Sanitize() {
+	r.Message = strings.TrimSpace(r.Message)
+}
+
+
+func (r ClaimPayload) Validate() error {
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.Message, validation.Length(0, 300)),
+	)
+}
@@ -0,0 +1,53 @@
+package api
+
+import (
+	"fmt"
+	"swap/models"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+)
+
+
+// ImageResponse describes an image of an item and where to fetch it
+type ImageResponse struct {
+	ID           uint    `json:"id"`
+	ItemId       uint    `json:"itemId"`
+	ContentType  string  `json:"contentType"`
+	Position     int     `json:"position"`
+	Size         int64   `json:"size"`
+	URL          string  `json:"url"`
+	Sizes        map[string]string `json:"sizes"` // URL of each resized variant
+}
+
+
+func NewImageResponse(image models.Image) ImageResponse {
+	url := fmt.Sprintf("/images/%d", image.ID)
+
+	sizes := map[string]string{}
+	for size := range models.ImageSizes {
+		sizes[size] = fmt.Sprintf("%s?size=%s", url, size)
+	}
+
+	return ImageResponse{
+		ID:          image.ID,
+		ItemId:      image.ItemId,
+		ContentType: image.ContentType,
+		Position:    image.Position,
+		Size:        image.Size,
+		URL:         url,
+		Sizes:       sizes,
+	}
+}
+
+
+// ReorderImagesPayload lists every image of an item in the order to show them
+type ReorderImagesPayload struct {
+	ImageIds  []uint  `json:"imageIds"`
+}
+
+
+func (r ReorderImagesPayload) Validate() error {
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.ImageIds, validation.Required),
+	)
+}
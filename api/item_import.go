@@ -0,0 +1,181 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"swap/models"
+)
+
+
+// maxImportLineSize is the longest JSON Lines row accepted
+const maxImportLineSize = 1 << 20
+
+
+// csvImportColumns maps the accepted CSV headers, in lower case, to the
+// RegisterItemPayload field they fill
+var csvImportColumns = map[string]string{
+	"name":         "name",
+	"categoryname": "categoryName",
+	"category":     "categoryName",
+	"description":  "description",
+	"prize":        "prize",
+	"price":        "prize",
+	"latitude":     "latitude",
+	"longitude":    "longitude",
+	"attributes":   "attributes",
+}
+
+
+// ParseItemImport reads an import file in the given format. Every row is
+// validated with the RegisterItemPayload rules; rows that fail carry their
+// error instead of an item. Only a malformed file as a whole returns an error.
+func ParseItemImport(reader io.Reader, format string) ([]models.ItemImportRow, error) {
+	switch strings.ToLower(format) {
+	case models.CSVImport:
+		return parseCSVImport(reader)
+	case models.JSONLImport, "ndjson":
+		return parseJSONLImport(reader)
+	}
+	return nil, fmt.Errorf("unsupported import format %q, use csv or jsonl", format)
+}
+
+
+func parseJSONLImport(reader io.Reader) ([]models.ItemImportRow, error) {
+	var rows []models.ItemImportRow
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), maxImportLineSize)
+
+	for line := 1; scanner.Scan(); line++ {
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+		if len(rows) == models.MaxImportRows {
+			return nil, fmt.Errorf("imports are limited to %d rows", models.MaxImportRows)
+		}
+
+		var payload RegisterItemPayload
+		decoder := json.NewDecoder(bytes.NewReader(text))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&payload); err != nil {
+			rows = append(rows, models.ItemImportRow{Line: line, Error: "Invalid JSON: " + err.Error()})
+			continue
+		}
+		rows = append(rows, importRow(line, payload))
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("could not read import: %v", err)
+	}
+	return rows, nil
+}
+
+
+func parseCSVImport(reader io.Reader) ([]models.ItemImportRow, error) {
+	var rows []models.ItemImportRow
+
+	csvReader := csv.NewReader(reader)
+	csvReader.TrimLeadingSpace = true
+
+	header, err := csvReader.Read()
+	if err != nil {
+		return nil, errors.New("could not read CSV header")
+	}
+
+	fields := make([]string, len(header))
+	for i, column := range header {
+		field, ok := csvImportColumns[strings.ToLower(strings.TrimSpace(column))]
+		if !ok {
+			return nil, fmt.Errorf("unknown CSV column %q", column)
+		}
+		fields[i] = field
+	}
+
+	for {
+		record, err := csvReader.Read()
+		if err == io.EOF {
+			break
+		}
+
+		if len(rows) == models.MaxImportRows {
+			return nil, fmt.Errorf("imports are limited to %d rows", models.MaxImportRows)
+		}
+
+		// Field positions are only known for records that parsed, so the line
+		// of a malformed one comes from the error
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				rows = append(rows, models.ItemImportRow{Line: parseErr.StartLine, Error: parseErr.Err.Error()})
+				continue
+			}
+			return nil, fmt.Errorf("could not read import: %v", err)
+		}
+		line, _ := csvReader.FieldPos(0)
+
+		payload, err := csvPayload(fields, record)
+		if err != nil {
+			rows = append(rows, models.ItemImportRow{Line: line, Error: err.Error()})
+			continue
+		}
+		rows = append(rows, importRow(line, payload))
+	}
+	return rows, nil
+}
+
+
+func csvPayload(fields, record []string) (RegisterItemPayload, error) {
+	var payload RegisterItemPayload
+
+	for i, value := range record {
+		value = strings.TrimSpace(value)
+		if i >= len(fields) || value == "" {
+			continue
+		}
+
+		switch fields[i] {
+		case "name":
+			payload.Name = value
+		case "categoryName":
+			payload.CategoryName = value
+		case "description":
+			payload.Description = value
+		case "prize", "latitude", "longitude":
+			number, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return payload, fmt.Errorf("%s: must be a number", fields[i])
+			}
+			switch fields[i] {
+			case "prize":
+				payload.Prize = number
+			case "latitude":
+				payload.Latitude = &number
+			case "longitude":
+				payload.Longitude = &number
+			}
+		case "attributes":
+			if err := json.Unmarshal([]byte(value), &payload.Attributes); err != nil {
+				return payload, errors.New("attributes: must be a JSON object")
+			}
+		}
+	}
+	return payload, nil
+}
+
+
+// importRow validates one payload exactly like a single registration would
+func importRow(line int, payload RegisterItemPayload) models.ItemImportRow {
+	payload.Sanitize()
+	if err := payload.Validate(); err != nil {
+		return models.ItemImportRow{Line: line, Error: err.Error()}
+	}
+	return models.ItemImportRow{Line: line, Item: payload.ToEntity()}
+}
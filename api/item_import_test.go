@@ -0,0 +1,51 @@
+package api
+
+import (
+	"strings"
+	"testing"
+)
+
+
+func TestParseCSVImportMalformedQuoting(t *testing.T) {
+	input := strings.Join([]string{
+		"name,category,price",
+		`a"b,PHONES,10`,
+		`Good phone,PHONES,20`,
+		`"abc,PHONES,10`,
+	}, "\n")
+
+	rows, err := ParseItemImport(strings.NewReader(input), "csv")
+	if err != nil {
+		t.Fatalf("ParseItemImport returned %v", err)
+	}
+
+	if len(rows) != 3 {
+		t.Fatalf("got %d rows, want 3: %+v", len(rows), rows)
+	}
+
+	if rows[0].Line != 2 || rows[0].Error == "" {
+		t.Errorf("bare quote row = %+v, want an error on line 2", rows[0])
+	}
+
+	if rows[1].Line != 3 || rows[1].Error != "" || rows[1].Item == nil || rows[1].Item.Name != "Good phone" {
+		t.Errorf("valid row = %+v, want the item on line 3", rows[1])
+	}
+
+	if rows[2].Line != 4 || rows[2].Error == "" {
+		t.Errorf("unterminated quote row = %+v, want an error on line 4", rows[2])
+	}
+}
+
+
+func TestParseCSVImportUnterminatedQuote(t *testing.T) {
+	input := "name,category,price\n\"abc,PHONES,10\n"
+
+	rows, err := ParseItemImport(strings.NewReader(input), "csv")
+	if err != nil {
+		t.Fatalf("ParseItemImport returned %v", err)
+	}
+
+	if len(rows) != 1 || rows[0].Line != 2 || rows[0].Error == "" {
+		t.Errorf("rows = %+v, want one error on line 2", rows)
+	}
+}
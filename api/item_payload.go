@@ -1,8 +1,10 @@
 package api
 
 import (
+	"errors"
 	"strings"
 	"swap/models"
+	"time"
 
 	validation "github.com/go-ozzo/ozzo-validation"
 )
@@ -14,26 +16,107 @@ type RegisterItemPayload struct {
 	Description	string	`json:"description"`
 	Prize		float64	`json:"prize"`
 	OwnerId     uint     `json:"ownerId"`
+	Attributes  models.Attributes `json:"attributes"`
+	Latitude    *float64 `json:"latitude"`
+	Longitude   *float64 `json:"longitude"`
+	Giveaway    bool     `json:"giveaway"`
+	ClaimMode   string   `json:"claimMode"`
+	ListingDays int      `json:"listingDays"` // Zero lists the item until it is sold
 }
 
 
 func (r RegisterItemPayload) Validate() error {
-	return validation.ValidateStruct(&r,
+	err := validation.ValidateStruct(&r,
 		validation.Field(&r.Name, validation.Required, validation.Length(3, 30)),
 		validation.Field(&r.Description, validation.Length(10, 300)),
 		validation.Field(&r.CategoryName, validation.Required),
-		validation.Field(&r.Prize, validation.Required, validation.Min(0.00)),
+		validation.Field(&r.Prize, validation.Min(0.00)),
+		validation.Field(&r.ListingDays, validation.Min(0), validation.Max(models.MaxListingDays)),
 	)
+	if err != nil {
+		return err
+	}
+
+	// Give-aways are free, everything else needs a price
+	if r.Giveaway {
+		if r.Prize != 0 {
+			return errors.New("prize: must be zero for a give-away")
+		}
+		if r.ClaimMode != "" && !models.IsValidClaimMode(strings.ToUpper(r.ClaimMode)) {
+			return errors.New("claimMode: must be OWNER_PICKS or FIRST_COME")
+		}
+	} else {
+		if r.Prize == 0 {
+			return errors.New("prize: cannot be blank")
+		}
+		if r.ClaimMode != "" {
+			return errors.New("claimMode: only applies to give-aways")
+		}
+	}
+	return validateCoordinates(r.Latitude, r.Longitude)
+}
+
+
+// validateCoordinates accepts either both or neither of latitude and longitude
+func validateCoordinates(latitude, longitude *float64) error {
+	if (latitude == nil) != (longitude == nil) {
+		return errors.New("latitude: latitude and longitude must be given together")
+	}
+	if latitude == nil {
+		return nil
+	}
+
+	coordinates := models.Coordinates{Latitude: *latitude, Longitude: *longitude}
+	if !coordinates.Valid() {
+		return errors.New("latitude: coordinates are out of range")
+	}
+	return nil
 }
 
-func (r RegisterItemPayload) Sanitize() {
+func (r *RegisterItemPayload) Sanitize() {
 	r.CategoryName = strings.TrimSpace(r.CategoryName)
 	r.CategoryName = strings.ToUpper(r.CategoryName)
+	r.ClaimMode = strings.ToUpper(strings.TrimSpace(r.ClaimMode))
+	if r.Giveaway && r.ClaimMode == "" {
+		r.ClaimMode = models.OwnerPicksClaims
+	}
+}
+
+
+func (r RegisterItemPayload) ToEntity() *models.Item {
+	return &models.Item{
+		Name:			r.Name,
+		CategoryName:	r.CategoryName,
+		Description:	r.Description,
+		Prize:			r.Prize,
+		OwnerId:		r.OwnerId,
+		Attributes:		r.Attributes,
+		Latitude:		r.Latitude,
+		Longitude:		r.Longitude,
+		Giveaway:		r.Giveaway,
+		ClaimMode:		r.ClaimMode,
+		ListingDays:	r.ListingDays,
+		ExpiresAt:		models.ListingExpiry(time.Now(), r.ListingDays),
+	}
 }
 
 
-type ItemSearchRequest struct {
-	SearchTerm string `json:"term"`
+// ItemQueryPayload is the body of a structured item search. Every filter is optional.
+type ItemQueryPayload struct {
+	Category      string             `json:"category"`
+	MinPrize      *float64           `json:"minPrize"`
+	MaxPrize      *float64           `json:"maxPrize"`
+	ListedAfter   *time.Time         `json:"listedAfter"`
+	ListedBefore  *time.Time         `json:"listedBefore"`
+	Sold          *bool              `json:"sold"`
+	Giveaway      *bool              `json:"giveaway"`
+	Location      string             `json:"location"`
+	Attributes    models.Attributes  `json:"attributes"`
+	Latitude      *float64           `json:"latitude"`
+	Longitude     *float64           `json:"longitude"`
+	Near          string             `json:"near"`      // An address, used when no coordinates are given
+	RadiusKm      float64            `json:"radiusKm"`
+	Sort          string             `json:"sort"`
 }
 
 
@@ -44,30 +127,98 @@ type ItemSearchResponse struct {
 	Prize		float64`json:"prize"`
 	UUID        string `json:"uuid"`
 	ID          int    `json:"id"`
+	Attributes  models.Attributes `json:"attributes"`
+	Latitude    *float64 `json:"latitude"`
+	Longitude   *float64 `json:"longitude"`
+	Distance    *float64 `json:"distance,omitempty"` // km from the searched location
+	Giveaway    bool     `json:"giveaway"`
 }
 
 
-func (r ItemSearchRequest) Sanitize() {
-	r.SearchTerm = strings.TrimSpace(r.SearchTerm)
+// ItemQueryResponse is one page of search results with facet counts for the whole result
+type ItemQueryResponse struct {
+	Items   []ItemSearchResponse  `json:"items"`
+	Facets  *models.ItemFacets    `json:"facets"`
 }
 
 
-func (r ItemSearchRequest) Validate() error {
-	return validation.ValidateStruct(&r,
-		validation.Field(&r.SearchTerm, validation.Required),
+func (r *ItemQueryPayload) Sanitize() {
+	r.Category = strings.ToUpper(strings.TrimSpace(r.Category))
+	r.Location = strings.TrimSpace(r.Location)
+	r.Near = strings.TrimSpace(r.Near)
+	r.Sort = strings.ToLower(strings.TrimSpace(r.Sort))
+}
+
+
+func (r ItemQueryPayload) Validate() error {
+	sortKeys := make([]interface{}, len(models.SortKeys))
+	for i, key := range models.SortKeys {
+		sortKeys[i] = key
+	}
+
+	err := validation.ValidateStruct(&r,
+		validation.Field(&r.MinPrize, validation.Min(0.00)),
+		validation.Field(&r.MaxPrize, validation.Min(0.00)),
+		validation.Field(&r.Sort, validation.In(sortKeys...)),
+		validation.Field(&r.RadiusKm, validation.Min(0.00)),
 	)
+	if err != nil {
+		return err
+	}
+
+	if err := validateCoordinates(r.Latitude, r.Longitude); err != nil {
+		return err
+	}
+
+	if r.MinPrize != nil && r.MaxPrize != nil && *r.MinPrize > *r.MaxPrize {
+		return errors.New("minPrize: cannot be greater than maxPrize")
+	}
+	if r.ListedAfter != nil && r.ListedBefore != nil && r.ListedAfter.After(*r.ListedBefore) {
+		return errors.New("listedAfter: cannot be later than listedBefore")
+	}
+	return nil
+}
+
+
+func (r ItemQueryPayload) ToQuery() models.ItemQuery {
+	var near *models.Coordinates
+	if r.Latitude != nil && r.Longitude != nil {
+		near = &models.Coordinates{Latitude: *r.Latitude, Longitude: *r.Longitude}
+	}
+
+	return models.ItemQuery{
+		Category:     r.Category,
+		MinPrize:     r.MinPrize,
+		MaxPrize:     r.MaxPrize,
+		ListedAfter:  r.ListedAfter,
+		ListedBefore: r.ListedBefore,
+		Sold:         r.Sold,
+		Giveaway:     r.Giveaway,
+		Location:     r.Location,
+		Attributes:   r.Attributes,
+		Near:         near,
+		NearAddress:  r.Near,
+		RadiusKm:     r.RadiusKm,
+		Sort:         r.Sort,
+	}
 }
 
 
 type ItemUpdatePayload struct {
 	Name		string 	`json:"name"`
 	Description	string 	`json:"description"`
-	Prize		float64 `json:"prize"`
+	Prize		*float64 `json:"prize"`
+	Attributes  models.Attributes `json:"attributes"`
+	Latitude    *float64 `json:"latitude"`
+	Longitude   *float64 `json:"longitude"`
 }
 
 
 func (r ItemUpdatePayload) Validate() error {
-	return nil
+	if err := validation.ValidateStruct(&r, validation.Field(&r.Prize, validation.Min(0.00))); err != nil {
+		return err
+	}
+	return validateCoordinates(r.Latitude, r.Longitude)
 }
 
 
@@ -80,9 +231,16 @@ func (r ItemUpdatePayload) ToEntity() models.Item {
 	if r.Description != "" {
 		item.Description = r.Description
 	}
-	if r.Prize >= 0.00 {
-		item.Prize = r.Prize
+	// A negative prize tells UpdateItem to leave the price alone
+	item.Prize = -1
+	if r.Prize != nil {
+		item.Prize = *r.Prize
+	}
+	if r.Attributes != nil {
+		item.Attributes = r.Attributes
 	}
+	item.Latitude = r.Latitude
+	item.Longitude = r.Longitude
 	return item
 }
 
@@ -115,3 +273,19 @@ func (r SwapItemPayload) Validate() error {
 		validation.Field(r.Amount, validation.Min(0.00)),
 	)
 }
+
+
+// RelistPayload restarts an item's listing, optionally at a lower price
+// or for a different number of days than before
+type RelistPayload struct {
+	Prize        *float64  `json:"prize"`
+	ListingDays  int       `json:"listingDays"`
+}
+
+
+func (r RelistPayload) Validate() error {
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.Prize, validation.Min(0.00)),
+		validation.Field(&r.ListingDays, validation.Min(0), validation.Max(models.MaxListingDays)),
+	)
+}
@@ -0,0 +1,25 @@
+package api
+
+import (
+	"strings"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+)
+
+
+// ReviewFlagPayload is a moderator's decision on a flagged listing
+type ReviewFlagPayload struct {
+	Status  string  `json:"status"`
+}
+
+
+func (r *ReviewFlagPayload) Sanitize() {
+	r.Status = strings.ToUpper(strings.TrimSpace(r.Status))
+}
+
+
+func (r ReviewFlagPayload) Validate() error {
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.Status, validation.Required),
+	)
+}
@@ -0,0 +1,41 @@
+package api
+
+import (
+	"strings"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+)
+
+
+type QuestionPayload struct {
+	Body  string  `json:"body"`
+}
+
+
+func (r *QuestionPayload) Sanitize() {
+	r.Body = strings.TrimSpace(r.Body)
+}
+
+
+func (r QuestionPayload) Validate() error {
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.Body, validation.Required, validation.Length(5, 500)),
+	)
+}
+
+
+type AnswerPayload struct {
+	Answer  string  `json:"answer"`
+}
+
+
+func (r *AnswerPayload) Sanitize() {
+	r.Answer = strings.TrimSpace(r.Answer)
+}
+
+
+func (r AnswerPayload) Validate() error {
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.Answer, validation.Required, validation.Length(1, 1000)),
+	)
+}
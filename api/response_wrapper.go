@@ -1,9 +1,17 @@
 package api
 
+import (
+	"strconv"
+	"swap/models"
+
+	"github.com/gin-gonic/gin"
+)
+
 type Response struct {
 	Code 				int		 		`json:"code" yaml:"code" example:"500"`
 	Message 			string    		`json:"message" yaml:"message"`
 	Details             interface{}		`json:"details" yaml:"details"`
+	Page                *models.Page    `json:"page,omitempty" yaml:"page,omitempty"`
 }
 
 
@@ -13,4 +21,21 @@ func NewResponse(Code int, Message string, Details interface{}) *Response {
 		Message: 	Message,
 		Details: 	Details,
 	}
-}
\ No newline at end of file
+}
+
+
+// NewPagedResponse wraps one page of a list together with its cursors and total count
+func NewPagedResponse(Code int, Message string, Details interface{}, Page *models.Page) *Response {
+	response := NewResponse(Code, Message, Details)
+	response.Page = Page
+	return response
+}
+
+
+// PageRequest reads the limit, page and cursor query parameters of a list endpoint
+func PageRequest(c *gin.Context) models.PageRequest {
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	page, _ := strconv.Atoi(c.Query("page"))
+
+	return models.NewPageRequest(limit, page, c.Query("cursor"))
+}
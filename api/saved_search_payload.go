@@ -0,0 +1,79 @@
+package api
+
+import (
+	"errors"
+	"strings"
+	"swap/models"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+)
+
+
+type SavedSearchPayload struct {
+	Name          string    `json:"name"`
+	CategoryName  string    `json:"categoryName"`
+	Keywords      string    `json:"keywords"`
+	MinPrize      *float64  `json:"minPrize"`
+	MaxPrize      *float64  `json:"maxPrize"`
+	Frequency     string    `json:"frequency"`
+}
+
+
+func (r *SavedSearchPayload) Sanitize() {
+	r.Name = strings.TrimSpace(r.Name)
+	r.CategoryName = strings.ToUpper(strings.TrimSpace(r.CategoryName))
+	r.Keywords = strings.TrimSpace(r.Keywords)
+	r.Frequency = strings.ToUpper(strings.TrimSpace(r.Frequency))
+	if r.Frequency == "" {
+		r.Frequency = models.InstantAlerts
+	}
+}
+
+
+func (r SavedSearchPayload) Validate() error {
+	err := validation.ValidateStruct(&r,
+		validation.Field(&r.Name, validation.Required, validation.Length(1, 50)),
+		validation.Field(&r.Keywords, validation.Length(0, 200)),
+		validation.Field(&r.MinPrize, validation.Min(0.00)),
+		validation.Field(&r.MaxPrize, validation.Min(0.00)),
+	)
+	if err != nil {
+		return err
+	}
+
+	if strings.TrimSpace(r.CategoryName) == "" && strings.TrimSpace(r.Keywords) == "" {
+		return errors.New("keywords: a saved search needs keywords or a category")
+	}
+	if r.MinPrize != nil && r.MaxPrize != nil && *r.MinPrize > *r.MaxPrize {
+		return errors.New("minPrize: cannot be greater than maxPrize")
+	}
+
+	frequency := strings.ToUpper(strings.TrimSpace(r.Frequency))
+	if frequency != "" && !models.IsValidFrequency(frequency) {
+		return errors.New("frequency: must be INSTANT or DAILY")
+	}
+	return nil
+}
+
+
+func (r SavedSearchPayload) ToEntity(userId uint) *models.SavedSearch {
+	return &models.SavedSearch{
+		UserId:       userId,
+		Name:         r.Name,
+		CategoryName: r.CategoryName,
+		Keywords:     r.Keywords,
+		MinPrize:     r.MinPrize,
+		MaxPrize:     r.MaxPrize,
+		Frequency:    r.Frequency,
+	}
+}
+
+
+type MarkNotificationsReadPayload struct {
+	Ids []uint `json:"ids"` // Empty marks every notification as read
+}
+
+
+func (r MarkNotificationsReadPayload) Validate() error {
+	return nil
+}
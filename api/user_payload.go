@@ -172,4 +172,19 @@ func (r FindUserByEmailOrUsernamePayload) Validate() error {
 	return validation.ValidateStruct(&r,
 		validation.Field(r.EmailOrUsername, validation.Required, validation.Length(3, 30)),
 	)
-}
\ No newline at end of file
+}
+
+
+type UpdateRolePayload struct {
+	Role		string		`json:"role"`
+}
+
+func (r *UpdateRolePayload) Sanitize() {
+	r.Role = strings.ToUpper(strings.TrimSpace(r.Role))
+}
+
+func (r UpdateRolePayload) Validate() error {
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.Role, validation.Required, validation.In(models.UserRole, models.AdminRole)),
+	)
+}
@@ -5,6 +5,7 @@ package apperrors
 const (
 	ServerError = "Something went wrong. Try again later."
 	Unauthorized = "Not authorized."
+	Forbidden = "You are not allowed to perform this action"
 )
 
 //Account Errors
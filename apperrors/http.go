@@ -143,6 +143,14 @@ func NewPayloadTooLarge(maxBodySize int64, contentLength int64) *Error {
 	}
 }
 
+// NewPayloadTooLargeWithMessage for 413 errors about limits other than the body size
+func NewPayloadTooLargeWithMessage(message string) *Error {
+	return &Error{
+		Type:    PayloadTooLarge,
+		Message: message,
+	}
+}
+
 // NewServiceUnavailable to create an error for 503
 func NewServiceUnavailable() *Error {
 	return &Error{
@@ -35,6 +35,10 @@ func InitDS() (*Ds, error) {
 
 	if err := db.AutoMigrate(
 		&models.User{}, &models.Item{}, &models.SwapRequest{}, &models.Category{}, &models.Image{},
+		&models.Notification{}, &models.SavedSearch{}, &models.Watch{},
+		&models.ItemView{}, &models.ItemDailyStat{}, &models.ItemSimilarity{}, &models.Transactions{},
+		&models.ItemRevision{}, &models.Claim{}, &models.Question{},
+		&models.ListingFlag{},
 	); err != nil {
 		return nil, fmt.Errorf("Error migrating models: %w", err)
 	}
@@ -50,4 +54,40 @@ func MigrateDB(db *gorm.DB) {
 	if err != nil {
 		log.Fatalf("Migration failed: %v", err)
 	}
-}
\ No newline at end of file
+
+	for _, statement := range migrations {
+		if err := db.Exec(statement).Error; err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+	}
+}
+
+
+// migrations holds schema changes AutoMigrate cannot express.
+// Every statement must be safe to run on each startup.
+var migrations = []string{
+	// Categories created before slugs existed get one derived from their name
+	`UPDATE categories SET slug = trim(both '-' from lower(regexp_replace(name, '[^a-zA-Z0-9]+', '-', 'g')))
+		WHERE slug IS NULL OR slug = ''`,
+
+	// Full-text search over items. The vector is a generated column so
+	// Postgres keeps it in sync on every insert and update.
+	`CREATE EXTENSION IF NOT EXISTS pg_trgm`,
+	`ALTER TABLE items ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+		setweight(to_tsvector('english', coalesce(name, '')), 'A') ||
+		setweight(to_tsvector('english', coalesce(category_name, '')), 'B') ||
+		setweight(to_tsvector('english', coalesce(description, '')), 'C')
+	) STORED`,
+	`CREATE INDEX IF NOT EXISTS idx_items_search_vector ON items USING GIN (search_vector)`,
+	`CREATE INDEX IF NOT EXISTS idx_items_name_trgm ON items USING GIN (name gin_trgm_ops)`,
+
+	// Items listed before revisions existed start from a first revision of their current details
+	`INSERT INTO item_revisions (created_at, item_id, revision, name, description, category_name, prize, attributes, latitude, longitude)
+		SELECT now(), id, revision, name, description, coalesce(category_name, ''), prize, attributes, latitude, longitude
+		FROM items WHERE NOT EXISTS (SELECT 1 FROM item_revisions r WHERE r.item_id = items.id)`,
+
+	// Prefix indexes for autocomplete on lower(column) LIKE 'prefix%'
+	`CREATE INDEX IF NOT EXISTS idx_items_name_prefix ON items (lower(name) text_pattern_ops)`,
+	`CREATE INDEX IF NOT EXISTS idx_categories_name_prefix ON categories (lower(name) text_pattern_ops)`,
+	`CREATE INDEX IF NOT EXISTS idx_users_user_name_prefix ON users (lower(user_name) text_pattern_ops)`,
+}
@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"swap/api"
+	"swap/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+
+// BlobHandler serves signed blob URLs for stores that cannot serve them
+// themselves, such as the local disk store
+type BlobHandler struct {
+	blobStore models.IBlobStore
+	verifier  models.IBlobURLVerifier
+}
+
+
+func NewBlobHandler(BlobStore models.IBlobStore, Verifier models.IBlobURLVerifier) *BlobHandler {
+	h := &BlobHandler{ blobStore: BlobStore, verifier: Verifier }
+	return h
+}
+
+
+func (h *BlobHandler) ServeSignedBlob(c *gin.Context) {
+	key := strings.TrimPrefix(c.Param("key"), "/")
+	expires, err := strconv.ParseInt(c.Query("expires"), 10, 64)
+
+	if err != nil || !h.verifier.VerifySignedURL(key, expires, c.Query("signature")) {
+		c.JSON(http.StatusForbidden, api.NewResponse(http.StatusForbidden, "Invalid or expired link", nil))
+		return
+	}
+
+	blob, info, err := h.blobStore.Get(c.Request.Context(), key)
+	if err != nil {
+		if errors.Is(err, models.ErrBlobNotFound) {
+			c.JSON(http.StatusNotFound, api.NewResponse(http.StatusNotFound, "File not found", nil))
+			return
+		}
+		log.Printf("Could not read blob %s: %v\n", key, err)
+		c.JSON(http.StatusInternalServerError, api.NewResponse(http.StatusInternalServerError, "Failed to read file", nil))
+		return
+	}
+	defer blob.Close()
+
+	c.DataFromReader(http.StatusOK, info.Size, info.ContentType, blob, nil)
+}
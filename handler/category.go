@@ -4,6 +4,7 @@ import (
 	"strings"
 	"net/http"
 	"swap/api"
+	"swap/apperrors"
 	"swap/models"
 
 	"strconv"
@@ -38,7 +39,9 @@ func (h *CategoryHandler) CreateCategory(c *gin.Context) {
 		return
 	}
 
-	category, err := h.categoryService.CreateCategory(strings.ToUpper(name))
+	parent, _ := request["parent"].(string)
+
+	category, err := h.categoryService.CreateCategory(strings.ToUpper(strings.TrimSpace(name)), strings.TrimSpace(parent))
 
 	if err != nil {
 		c.JSON(http.StatusBadRequest, api.NewResponse(http.StatusBadRequest, "Unable to add category", nil))
@@ -170,18 +173,87 @@ func (h *CategoryHandler) GetAllItemsInCategory(c *gin.Context) {
 	routeId := c.Param("id")
 	categoryId, _ := strconv.Atoi(routeId)
 
-	limit := c.Query("limit")
-	page := c.Query("page")
+	items, page, err := h.categoryService.GetAllItemsInCategory(categoryId, api.PageRequest(c))
+
+	if err != nil {
+		c.JSON(http.StatusBadRequest, api.NewResponse(http.StatusBadRequest, "Couldnt get items", nil))
+		return
+	}
 
-	limitValue, _ := strconv.Atoi(limit)
-	pageValue, _ := strconv.Atoi(page)
+	c.JSON(http.StatusOK, api.NewPagedResponse(http.StatusOK, "Successful", items, page))
+}
 
-	items, err := h.categoryService.GetAllItemsInCategory(categoryId, limitValue, pageValue)
 
+func (h *CategoryHandler) UpdateAttributeSchema(c *gin.Context) {
+	routeId := c.Param("id")
+	categoryId, err := strconv.Atoi(routeId)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, api.NewResponse(http.StatusBadRequest, "Couldnt get items", nil))
+		c.JSON(http.StatusBadRequest, api.NewResponse(http.StatusBadRequest, "Invalid category ID", nil))
+		return
+	}
+
+	var request api.AttributeSchemaPayload
+	if ok := api.BindData(c, &request); !ok {
 		return
 	}
 
-	c.JSON(http.StatusOK, api.NewResponse(http.StatusOK, "Successful", items))
-}
\ No newline at end of file
+	err = h.categoryService.UpdateAttributeSchema(categoryId, request.Schema)
+	if err != nil {
+		e := apperrors.GetAppError(err, "Unable to update attribute schema")
+		c.JSON(e.Status(), api.NewResponse(e.Status(), "Unable to update attribute schema", e.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, api.NewResponse(http.StatusOK, "Successful", request.Schema))
+}
+
+
+func (h *CategoryHandler) RenameCategory(c *gin.Context) {
+	var request api.RenameCategoryPayload
+	if ok := api.BindData(c, &request); !ok {
+		return
+	}
+
+	report, err := h.categoryService.RenameCategory(request.Name, request.NewName, request.DryRun)
+	if err != nil {
+		e := apperrors.GetAppError(err, "Unable to rename category")
+		c.JSON(e.Status(), api.NewResponse(e.Status(), "Unable to rename category", e.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, api.NewResponse(http.StatusOK, "Successful", report))
+}
+
+
+func (h *CategoryHandler) MergeCategory(c *gin.Context) {
+	var request api.MergeCategoryPayload
+	if ok := api.BindData(c, &request); !ok {
+		return
+	}
+
+	report, err := h.categoryService.MergeCategory(strings.TrimSpace(request.Source), strings.TrimSpace(request.Target), request.DryRun)
+	if err != nil {
+		e := apperrors.GetAppError(err, "Unable to merge categories")
+		c.JSON(e.Status(), api.NewResponse(e.Status(), "Unable to merge categories", e.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, api.NewResponse(http.StatusOK, "Successful", report))
+}
+
+
+func (h *CategoryHandler) ReassignItems(c *gin.Context) {
+	var request api.ReassignItemsPayload
+	if ok := api.BindData(c, &request); !ok {
+		return
+	}
+
+	report, err := h.categoryService.ReassignItems(request.ItemIds, strings.TrimSpace(request.Target), request.DryRun)
+	if err != nil {
+		e := apperrors.GetAppError(err, "Unable to reassign items")
+		c.JSON(e.Status(), api.NewResponse(e.Status(), "Unable to reassign items", e.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, api.NewResponse(http.StatusOK, "Successful", report))
+}
@@ -0,0 +1,162 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"swap/api"
+	"swap/apperrors"
+	"swap/middleware"
+	"swap/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+
+type ClaimHandler struct {
+	claimService models.IClaimService
+}
+
+
+func NewClaimHandler(ClaimService models.IClaimService) *ClaimHandler {
+	h := &ClaimHandler{ claimService: ClaimService }
+	return h
+}
+
+
+func (h *ClaimHandler) CreateClaim(c *gin.Context) {
+	itemId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		ToFieldErrorResponse(c, "id", "Invalid item ID")
+		return
+	}
+
+	// The message is optional, so an empty body is fine
+	var request api.ClaimPayload
+	if c.Request.ContentLength != 0 {
+		if ok := api.BindData(c, &request); !ok {
+			return
+		}
+		request.Sanitize()
+	}
+
+	userDetails, _ := c.Get("id")
+	if userDetails == nil {
+		c.JSON(http.StatusUnauthorized, api.NewResponse(http.StatusUnauthorized, "User not authenticated", nil))
+		return
+	}
+	userId := userDetails.(*middleware.User).ID
+
+	claim, err := h.claimService.CreateClaim(userId, uint(itemId), request.Message)
+	if err != nil {
+		e := apperrors.GetAppError(err, "Unable to claim item")
+		c.JSON(e.Status(), api.NewResponse(e.Status(), "Unable to claim item", gin.H{ "error" : e, }))
+		return
+	}
+
+	c.JSON(http.StatusCreated, api.NewResponse(http.StatusCreated, "Successful", claim))
+}
+
+
+func (h *ClaimHandler) WithdrawClaim(c *gin.Context) {
+	itemId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		ToFieldErrorResponse(c, "id", "Invalid item ID")
+		return
+	}
+
+	userDetails, _ := c.Get("id")
+	if userDetails == nil {
+		c.JSON(http.StatusUnauthorized, api.NewResponse(http.StatusUnauthorized, "User not authenticated", nil))
+		return
+	}
+	userId := userDetails.(*middleware.User).ID
+
+	if err := h.claimService.WithdrawClaim(userId, uint(itemId)); err != nil {
+		e := apperrors.GetAppError(err, "Unable to withdraw claim")
+		c.JSON(e.Status(), api.NewResponse(e.Status(), "Unable to withdraw claim", gin.H{ "error" : e, }))
+		return
+	}
+
+	c.JSON(http.StatusOK, api.NewResponse(http.StatusOK, "Claim withdrawn", nil))
+}
+
+
+func (h *ClaimHandler) GetClaims(c *gin.Context) {
+	itemId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		ToFieldErrorResponse(c, "id", "Invalid item ID")
+		return
+	}
+
+	userDetails, _ := c.Get("id")
+	if userDetails == nil {
+		c.JSON(http.StatusUnauthorized, api.NewResponse(http.StatusUnauthorized, "User not authenticated", nil))
+		return
+	}
+	actor := userDetails.(*middleware.User).Actor()
+
+	claims, page, err := h.claimService.GetClaims(actor, uint(itemId), api.PageRequest(c))
+	if err != nil {
+		e := apperrors.GetAppError(err, "Couldnt get claims")
+		c.JSON(e.Status(), api.NewResponse(e.Status(), "Couldnt get claims", gin.H{ "error" : e, }))
+		return
+	}
+
+	c.JSON(http.StatusOK, api.NewPagedResponse(http.StatusOK, "Successful", claims, page))
+}
+
+
+func (h *ClaimHandler) ChooseClaim(c *gin.Context) {
+	itemId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		ToFieldErrorResponse(c, "id", "Invalid item ID")
+		return
+	}
+
+	claimId, err := strconv.Atoi(c.Param("claimId"))
+	if err != nil {
+		ToFieldErrorResponse(c, "claimId", "Invalid claim ID")
+		return
+	}
+
+	userDetails, _ := c.Get("id")
+	if userDetails == nil {
+		c.JSON(http.StatusUnauthorized, api.NewResponse(http.StatusUnauthorized, "User not authenticated", nil))
+		return
+	}
+	actor := userDetails.(*middleware.User).Actor()
+
+	claim, err := h.claimService.ChooseClaim(actor, uint(itemId), uint(claimId))
+	if err != nil {
+		e := apperrors.GetAppError(err, "Unable to choose recipient")
+		c.JSON(e.Status(), api.NewResponse(e.Status(), "Unable to choose recipient", gin.H{ "error" : e, }))
+		return
+	}
+
+	c.JSON(http.StatusOK, api.NewResponse(http.StatusOK, "Successful", claim))
+}
+
+
+func (h *ClaimHandler) CompleteClaim(c *gin.Context) {
+	itemId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		ToFieldErrorResponse(c, "id", "Invalid item ID")
+		return
+	}
+
+	userDetails, _ := c.Get("id")
+	if userDetails == nil {
+		c.JSON(http.StatusUnauthorized, api.NewResponse(http.StatusUnauthorized, "User not authenticated", nil))
+		return
+	}
+	actor := userDetails.(*middleware.User).Actor()
+
+	claim, err := h.claimService.CompleteClaim(actor, uint(itemId))
+	if err != nil {
+		e := apperrors.GetAppError(err, "Unable to complete give-away")
+		c.JSON(e.Status(), api.NewResponse(e.Status(), "Unable to complete give-away", gin.H{ "error" : e, }))
+		return
+	}
+
+	c.JSON(http.StatusOK, api.NewResponse(http.StatusOK, "Successful", claim))
+}
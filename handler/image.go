@@ -1,9 +1,12 @@
 package handler
 
 import (
+	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"bytes"
 	"image"
@@ -12,6 +15,8 @@ import (
 	"image/jpeg"
 
 	"swap/api"
+	"swap/apperrors"
+	"swap/middleware"
 	"swap/models"
 
 	"github.com/gin-gonic/gin"
@@ -28,24 +33,109 @@ func NewImageHandler(ImageService models.IImageService) *ImageHandler {
 }
 
 
+func (h *ImageHandler) ServeImage(c *gin.Context) {
+	imageId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		ToFieldErrorResponse(c, "id", "Invalid image ID")
+		return
+	}
+
+	size, ok := imageSize(c)
+	if !ok {
+		return
+	}
+
+	content, err := h.imageService.OpenImage(imageId, size)
+	if err != nil {
+		e := apperrors.GetAppError(err, "Could not read image")
+		if e.Type == apperrors.Internal {
+			log.Printf("Could not read image %d: %v\n", imageId, err)
+		}
+		c.JSON(e.Status(), api.NewResponse(e.Status(), "Could not read image", gin.H{ "error" : e, }))
+		return
+	}
+
+	serveImage(c, content)
+}
+
+
 func (h *ImageHandler) ReadFirstImageById(c *gin.Context) {
 	id := c.Param("id")
 	itemId, _ := strconv.Atoi(id)
 
-	image, err := h.imageService.ReadFirstImageById(itemId)
+	size, ok := imageSize(c)
+	if !ok {
+		return
+	}
+
+	content, err := h.imageService.OpenFirstImage(itemId, size)
 
 	if err != nil {
 		log.Print("Could not get any image")
-		placeHolderImage := generatePlaceHolderImage()
+		placeHolderImage := generatePlaceHolderImage(size)
 		c.Data(http.StatusOK, "image/jpeg", placeHolderImage)
 		return
 	}
 
-	c.Data(http.StatusOK, "image/jpeg", image)
+	serveImage(c, content)
 }
 
-func generatePlaceHolderImage() []byte {
-	img := image.NewRGBA(image.Rect(0, 0, 200, 200))
+
+// imageSize reads the optional size query parameter, responding with a field
+// error when it is not one of models.ImageSizes
+func imageSize(c *gin.Context) (string, bool) {
+	size := c.Query("size")
+	if size != "" && !models.IsValidImageSize(size) {
+		ToFieldErrorResponse(c, "size", "Size must be thumbnail, medium or large")
+		return "", false
+	}
+	return size, true
+}
+
+
+// serveImage streams an image with Range, ETag and Last-Modified support.
+// Images stored before content types were recorded are sniffed, and anything
+// that is not an image is sent as a download so browsers never render it.
+func serveImage(c *gin.Context, content *models.ImageContent) {
+	defer content.Close()
+
+	contentType := content.Image.ContentType
+	if contentType == "" {
+		head := make([]byte, 512)
+		n, _ := io.ReadFull(content.Content, head)
+		contentType = http.DetectContentType(head[:n])
+		if _, err := content.Content.Seek(0, io.SeekStart); err != nil {
+			c.JSON(http.StatusInternalServerError, api.NewResponse(http.StatusInternalServerError, "Failed to read image", nil))
+			return
+		}
+	}
+
+	if !strings.HasPrefix(contentType, "image/") {
+		contentType = "application/octet-stream"
+		c.Header("Content-Disposition", "attachment")
+	}
+
+	etag := content.Image.Checksum
+	if etag == "" {
+		etag = fmt.Sprintf("%d-%d", content.Image.ID, content.ModTime.Unix())
+	}
+
+	c.Header("Content-Type", contentType)
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Header("ETag", `"`+etag+`"`)
+	http.ServeContent(c.Writer, c.Request, "", content.ModTime, content.Content)
+}
+
+
+// generatePlaceHolderImage draws a blank square as large as the requested
+// size, or 200 pixels across when no size was asked for
+func generatePlaceHolderImage(size string) []byte {
+	dimension := 200
+	if bound, ok := models.ImageSizes[size]; ok {
+		dimension = bound
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, dimension, dimension))
 	draw.Draw(img, img.Bounds(), &image.Uniform{C: color.RGBA{255, 255, 255, 255}}, image.Point{}, draw.Src)
 
 	buf := new(bytes.Buffer)
@@ -56,14 +146,9 @@ func generatePlaceHolderImage() []byte {
 
 func (h *ImageHandler) ReadAllImagesByItemId(c *gin.Context) {
 	id := c.Param("id")
-	limit := c.Query("limit")
-	page := c.Query("page")
-
 	itemId, _  := strconv.Atoi(id) 
-	limitValue, _ := strconv.Atoi(limit)
-	pageValue, _ := strconv.Atoi(page)
 
-	images, err := h.imageService.ReadAllImagesByItemId(itemId, limitValue, pageValue)
+	images, page, err := h.imageService.ReadAllImagesByItemId(itemId, api.PageRequest(c))
 
 	if err != nil {
 		log.Print("Cannot get images for this item")
@@ -71,41 +156,106 @@ func (h *ImageHandler) ReadAllImagesByItemId(c *gin.Context) {
 		return
 	}
 
-	var imagePaths []string
+	c.JSON(http.StatusOK, api.NewPagedResponse(http.StatusOK, "Successful", imageResponses(images), page))
+}
 
-	for _, image := range images {
-		imagePaths = append(imagePaths, image.FilePath+"/"+image.FileName)
+
+func (h *ImageHandler) DeleteImage(c *gin.Context) {
+	itemId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		ToFieldErrorResponse(c, "id", "Invalid item ID")
+		return
+	}
+
+	imageId, err := strconv.Atoi(c.Param("imageId"))
+	if err != nil {
+		ToFieldErrorResponse(c, "imageId", "Invalid image ID")
+		return
 	}
 
-	c.JSON(http.StatusOK, api.NewResponse(http.StatusOK, "Successful", imagePaths))
+	userDetails, _ := c.Get("id")
+	if userDetails == nil {
+		c.JSON(http.StatusUnauthorized, api.NewResponse(http.StatusUnauthorized, "User not authenticated", nil))
+		return
+	}
+	actor := userDetails.(*middleware.User).Actor()
+
+	if err := h.imageService.DeleteImage(actor, uint(itemId), uint(imageId)); err != nil {
+		e := apperrors.GetAppError(err, "Unable to delete image")
+		c.JSON(e.Status(), api.NewResponse(e.Status(), "Unable to delete image", gin.H{ "error" : e, }))
+		return
+	}
+
+	c.JSON(http.StatusOK, api.NewResponse(http.StatusOK, "Image deleted", nil))
 }
 
-func (h *ImageHandler) ReadImage(c *gin.Context) {
-	data, err := h.imageService.ReadImage("item_17", "1735237103_FB_IMG_16159860997307807.jpg")
-	
+
+func (h *ImageHandler) ReorderImages(c *gin.Context) {
+	itemId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		ToFieldErrorResponse(c, "id", "Invalid item ID")
+		return
+	}
+
+	var request api.ReorderImagesPayload
+	if ok := api.BindData(c, &request); !ok {
+		return
+	}
+
+	userDetails, _ := c.Get("id")
+	if userDetails == nil {
+		c.JSON(http.StatusUnauthorized, api.NewResponse(http.StatusUnauthorized, "User not authenticated", nil))
+		return
+	}
+	actor := userDetails.(*middleware.User).Actor()
+
+	images, err := h.imageService.ReorderImages(actor, uint(itemId), request.ImageIds)
 	if err != nil {
-		log.Print("Failed to read image")
-		c.JSON(http.StatusInternalServerError, api.NewResponse(http.StatusInternalServerError, "Failed to read image", nil))
+		e := apperrors.GetAppError(err, "Unable to reorder images")
+		c.JSON(e.Status(), api.NewResponse(e.Status(), "Unable to reorder images", gin.H{ "error" : e, }))
+		return
 	}
 
-	c.Data(http.StatusOK, "image/jpeg", data)
+	c.JSON(http.StatusOK, api.NewResponse(http.StatusOK, "Successful", imageResponses(images)))
 }
 
 
-func (h *ImageHandler) ReadImageByPath(c *gin.Context) {
-	imagePath := c.Query("imagePath")
+func (h *ImageHandler) SetCoverImage(c *gin.Context) {
+	itemId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		ToFieldErrorResponse(c, "id", "Invalid item ID")
+		return
+	}
 
-	if imagePath == "" {
-		log.Print("Image path query parameter is required")
-		c.JSON(http.StatusBadRequest, api.NewResponse(http.StatusBadRequest, "Image path query parameter is required", nil))
+	imageId, err := strconv.Atoi(c.Param("imageId"))
+	if err != nil {
+		ToFieldErrorResponse(c, "imageId", "Invalid image ID")
+		return
 	}
 
-	data, err := h.imageService.ReadImageByPath(imagePath)
-	
+	userDetails, _ := c.Get("id")
+	if userDetails == nil {
+		c.JSON(http.StatusUnauthorized, api.NewResponse(http.StatusUnauthorized, "User not authenticated", nil))
+		return
+	}
+	actor := userDetails.(*middleware.User).Actor()
+
+	images, err := h.imageService.SetCoverImage(actor, uint(itemId), uint(imageId))
 	if err != nil {
-		log.Print("Failed to read image")
-		c.JSON(http.StatusInternalServerError, api.NewResponse(http.StatusInternalServerError, "Failed to read image", nil))
+		e := apperrors.GetAppError(err, "Unable to set cover image")
+		c.JSON(e.Status(), api.NewResponse(e.Status(), "Unable to set cover image", gin.H{ "error" : e, }))
+		return
 	}
 
-	c.Data(http.StatusOK, "image/jpeg", data)
+	c.JSON(http.StatusOK, api.NewResponse(http.StatusOK, "Successful", imageResponses(images)))
+}
+
+
+func imageResponses(images []models.Image) []api.ImageResponse {
+	responses := []api.ImageResponse{}
+
+	for _, image := range images {
+		responses = append(responses, api.NewImageResponse(image))
+	}
+	return responses
 }
@@ -1,11 +1,13 @@
 package handler
 
 import (
+	"io"
 	"log"
+	"path/filepath"
 	"net/http"
 	"strings"
 	"strconv"
-	"swap/utils"
+	"time"
 	"swap/api"
 	"swap/apperrors"
 	"swap/middleware"
@@ -16,13 +18,14 @@ import (
 
 
 type ItemHandler struct {
-	itemService models.IItemService
-	Utils *utils.Utils
+	itemService      models.IItemService
+	analyticsService models.IAnalyticsService
+	questionService  models.IQuestionService
 }
 
 
-func NewItemHandler(ItemService models.IItemService, util *utils.Utils) *ItemHandler{
-	h := &ItemHandler{ itemService: ItemService, Utils: util }
+func NewItemHandler(ItemService models.IItemService, AnalyticsService models.IAnalyticsService, QuestionService models.IQuestionService) *ItemHandler{
+	h := &ItemHandler{ itemService: ItemService, analyticsService: AnalyticsService, questionService: QuestionService }
 	return h
 }
 
@@ -49,15 +52,7 @@ func (h *ItemHandler) RegisterItem(c *gin.Context) {
 	userId := userDetails.(*middleware.User).ID
 	request.OwnerId = userId
 
-	registerItemPayload := &models.Item{
-		Name:			request.Name,
-		CategoryName:	request.CategoryName,
-		Description:	request.Description,
-		Prize:			request.Prize,
-		OwnerId:		request.OwnerId,
-	}
-
-	item, err := h.itemService.RegisterItem(registerItemPayload)
+	item, err := h.itemService.RegisterItem(request.ToEntity())
 	if err != nil {
 		log.Print("Unable to register item")
 		c.JSON(apperrors.Status(err), api.NewResponse(apperrors.Status(err), "Unable to register item", gin.H{
@@ -70,6 +65,71 @@ func (h *ItemHandler) RegisterItem(c *gin.Context) {
 }
 
 
+// maxImportSize caps the size of an uploaded import file
+const maxImportSize = 10 << 20
+
+
+// ImportItems registers items in bulk from a CSV or JSON Lines file, sent either
+// as the "file" field of a multipart form or as the raw request body
+func (h *ItemHandler) ImportItems(c *gin.Context) {
+	userDetails, _ := c.Get("id")
+	if userDetails == nil {
+		c.JSON(http.StatusUnauthorized, api.NewResponse(http.StatusUnauthorized, "User not authenticated", nil))
+		return
+	}
+	userId := userDetails.(*middleware.User).ID
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize)
+
+	body := io.Reader(c.Request.Body)
+	format := c.Query("format")
+
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			ToFieldErrorResponse(c, "file", "Import file is required")
+			return
+		}
+
+		file, err := fileHeader.Open()
+		if err != nil {
+			e := apperrors.NewInternal()
+			c.JSON(e.Status(), api.NewResponse(e.Status(), "Could not read import file", nil))
+			return
+		}
+		defer file.Close()
+
+		body = file
+		if format == "" {
+			format = strings.TrimPrefix(strings.ToLower(filepath.Ext(fileHeader.Filename)), ".")
+		}
+	} else if format == "" {
+		switch c.ContentType() {
+		case "text/csv":
+			format = models.CSVImport
+		case "application/x-ndjson", "application/jsonl":
+			format = models.JSONLImport
+		}
+	}
+
+	rows, err := api.ParseItemImport(body, format)
+	if err != nil {
+		ToFieldErrorResponse(c, "file", err.Error())
+		return
+	}
+
+	dryRun := c.Query("dryRun") == "true"
+	report, err := h.itemService.ImportItems(userId, rows, dryRun)
+	if err != nil {
+		e := apperrors.GetAppError(err, "Unable to import items")
+		c.JSON(e.Status(), api.NewResponse(e.Status(), "Unable to import items", gin.H{ "error" : e, }))
+		return
+	}
+
+	c.JSON(http.StatusOK, api.NewResponse(http.StatusOK, "Successful", report))
+}
+
+
 func (h *ItemHandler) GetItemById(c *gin.Context) {
 	routeId := c.Param("id")
 	itemId, _ := strconv.Atoi(routeId)
@@ -84,68 +144,142 @@ func (h *ItemHandler) GetItemById(c *gin.Context) {
 		return
 	}
 
+	userDetails, _ := c.Get("id")
+	user, _ := userDetails.(*middleware.User)
+	if item.ID != 0 && user != nil && user.ID != item.OwnerId {
+		go h.analyticsService.RecordView(item.ID, viewerKey(user))
+	}
+
+	// Only answered questions are public, so their first page is shown
+	// alongside the item with the cursor for the next
+	if item.ID != 0 {
+		questions, page, err := h.questionService.GetQuestions(models.Actor{}, item.ID, models.NewPageRequest(0, 1, ""))
+		if err != nil {
+			log.Printf("Could not load questions of item %d: %v\n", item.ID, err)
+		}
+		item.Questions = questions
+		item.QuestionsPage = page
+	}
+
 	c.JSON(http.StatusOK, api.NewResponse(http.StatusOK, "Successful", item))
 }
 
 
-func (h *ItemHandler) GetItemsByCategory(c *gin.Context) {
-	limit := c.Query("limit")
-	page := c.Query("page")
+// viewerKey identifies who is viewing an item, so repeat views on the same
+// day count once. Items are only shown to logged in users.
+func viewerKey(user *middleware.User) string {
+	return "user:" + strconv.Itoa(int(user.ID))
+}
 
-	var request api.ItemSearchRequest
-	if ok := api.BindData(c, &request); !ok {
+
+func (h *ItemHandler) GetItemRevisions(c *gin.Context) {
+	itemId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		ToFieldErrorResponse(c, "id", "Invalid item ID")
 		return
 	}
 
-	request.Sanitize()
-	limitValue, _ := strconv.Atoi(limit)
-	pageValue, _ := strconv.Atoi(page)
-
-	items, err := h.itemService.GetItemsByCategory(strings.ToUpper(request.SearchTerm), limitValue, pageValue)
+	revisions, err := h.itemService.GetItemRevisions(itemId)
 	if err != nil {
-		e := apperrors.NewInternal()
-		c.JSON(e.Status(), api.NewResponse(e.Status(), "Couldnt get items", gin.H{ "error" : e, }))
+		e := apperrors.GetAppError(err, "Couldnt get item revisions")
+		c.JSON(e.Status(), api.NewResponse(e.Status(), "Couldnt get item revisions", gin.H{ "error" : e, }))
 		return
 	}
 
-	var responses []api.ItemSearchResponse
+	c.JSON(http.StatusOK, api.NewResponse(http.StatusOK, "Successful", revisions))
+}
 
-	for _, item := range items {
-		response := api.ItemSearchResponse{
-			Name:	 		item.Name,
-			CategoryName:	item.CategoryName,
-			Description:	item.Description,
-			Prize:			item.Prize,
-			UUID:			item.UUID.String(),
-			ID:				int(item.ID),
+
+func (h *ItemHandler) RelistItem(c *gin.Context) {
+	itemId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		ToFieldErrorResponse(c, "id", "Invalid item ID")
+		return
+	}
+
+	// Both the price and the duration are optional, so an empty body is fine
+	var request api.RelistPayload
+	if c.Request.ContentLength != 0 {
+		if ok := api.BindData(c, &request); !ok {
+			return
 		}
-		responses = append(responses, response)
 	}
 
-	c.JSON(http.StatusOK, api.NewResponse(http.StatusOK, "Successful", responses))
+	userDetails, _ := c.Get("id")
+	if userDetails == nil {
+		c.JSON(http.StatusUnauthorized, api.NewResponse(http.StatusUnauthorized, "User not authenticated", nil))
+		return
+	}
+	actor := userDetails.(*middleware.User).Actor()
+
+	if err := h.itemService.RelistItem(actor, itemId, request.Prize, request.ListingDays); err != nil {
+		e := apperrors.GetAppError(err, "Unable to relist item")
+		c.JSON(e.Status(), api.NewResponse(e.Status(), "Unable to relist item", gin.H{ "error" : e, }))
+		return
+	}
+
+	c.JSON(http.StatusOK, api.NewResponse(http.StatusOK, "Item relisted", nil))
 }
 
 
-func (h *ItemHandler) GetUnsoldItemsByCategory(c *gin.Context) {
-	limit := c.Query("limit")
-	page := c.Query("page")
+func (h *ItemHandler) GetItemAnalytics(c *gin.Context) {
+	itemId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		ToFieldErrorResponse(c, "id", "Invalid item ID")
+		return
+	}
 
-	var request api.ItemSearchRequest
+	userDetails, _ := c.Get("id")
+	if userDetails == nil {
+		c.JSON(http.StatusUnauthorized, api.NewResponse(http.StatusUnauthorized, "User not authenticated", nil))
+		return
+	}
+	actor := userDetails.(*middleware.User).Actor()
+
+	to := time.Now()
+	if value := c.Query("to"); value != "" {
+		if to, err = time.Parse(dateLayout, value); err != nil {
+			ToFieldErrorResponse(c, "to", "Dates must look like 2006-01-02")
+			return
+		}
+	}
+
+	from := to.AddDate(0, 0, 1-models.DefaultAnalyticsDays)
+	if value := c.Query("from"); value != "" {
+		if from, err = time.Parse(dateLayout, value); err != nil {
+			ToFieldErrorResponse(c, "from", "Dates must look like 2006-01-02")
+			return
+		}
+	}
+
+	analytics, err := h.analyticsService.GetItemAnalytics(actor, itemId, from, to)
+	if err != nil {
+		e := apperrors.GetAppError(err, "Couldnt get item analytics")
+		c.JSON(e.Status(), api.NewResponse(e.Status(), "Couldnt get item analytics", gin.H{ "error" : e, }))
+		return
+	}
+
+	c.JSON(http.StatusOK, api.NewResponse(http.StatusOK, "Successful", analytics))
+}
+
+
+func (h *ItemHandler) QueryItems(c *gin.Context) {
+	var request api.ItemQueryPayload
 	if ok := api.BindData(c, &request); !ok {
 		return
 	}
 
 	request.Sanitize()
-	limitValue, _ := strconv.Atoi(limit)
-	pageValue, _ := strconv.Atoi(page)
-    
-	items, err := h.itemService.GetUnsoldItemsByCategory(strings.ToUpper(request.SearchTerm), limitValue, pageValue)
+
+	query := request.ToQuery()
+	items, facets, page, err := h.itemService.QueryItems(query, api.PageRequest(c))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, api.NewResponse(http.StatusBadRequest, "Couldnt get items", nil))
+		e := apperrors.GetAppError(err, "Couldnt get items")
+		c.JSON(e.Status(), api.NewResponse(e.Status(), "Couldnt get items", gin.H{ "error" : e, }))
 		return
 	}
 
-	var responses []api.ItemSearchResponse
+	responses := []api.ItemSearchResponse{}
 
 	for _, item := range items {
 		response := api.ItemSearchResponse{
@@ -155,11 +289,41 @@ func (h *ItemHandler) GetUnsoldItemsByCategory(c *gin.Context) {
 			Prize:			item.Prize,
 			UUID:			item.UUID.String(),
 			ID:				int(item.ID),
+			Attributes:		item.Attributes,
+			Latitude:		item.Latitude,
+			Longitude:		item.Longitude,
+			Giveaway:		item.Giveaway,
+		}
+		if query.Near != nil && item.Coordinates() != nil {
+			distance := query.Near.DistanceKm(*item.Coordinates())
+			response.Distance = &distance
 		}
 		responses = append(responses, response)
 	}
 
-	c.JSON(http.StatusOK, api.NewResponse(http.StatusOK, "Successful", responses))
+	result := api.ItemQueryResponse{
+		Items:	responses,
+		Facets:	facets,
+	}
+	c.JSON(http.StatusOK, api.NewPagedResponse(http.StatusOK, "Successful", result, page))
+}
+
+
+func (h *ItemHandler) SearchItems(c *gin.Context) {
+	query := strings.TrimSpace(c.Query("q"))
+	if query == "" {
+		ToFieldErrorResponse(c, "q", "Search query is required")
+		return
+	}
+
+	hits, page, err := h.itemService.SearchItems(query, api.PageRequest(c))
+	if err != nil {
+		e := apperrors.GetAppError(err, "Couldnt search items")
+		c.JSON(e.Status(), api.NewResponse(e.Status(), "Couldnt search items", gin.H{ "error" : e, }))
+		return
+	}
+
+	c.JSON(http.StatusOK, api.NewPagedResponse(http.StatusOK, "Successful", hits, page))
 }
 
 
@@ -173,7 +337,7 @@ func (h *ItemHandler) UpdateItem(c *gin.Context) {
 		return
 	}
 
-	userId := userDetails.(*middleware.User).ID
+	actor := userDetails.(*middleware.User).Actor()
 
 	var request api.ItemUpdatePayload
 	if ok := api.BindData(c, &request); !ok {
@@ -187,17 +351,10 @@ func (h *ItemHandler) UpdateItem(c *gin.Context) {
 	id, _ := strconv.Atoi(itemId)
 	item.ID = uint(id)
 
-	foundItem, _ := h.itemService.GetItemById(id)
-	
-	if foundItem.OwnerId != userId {
-		c.JSON(http.StatusBadRequest, api.NewResponse(http.StatusBadRequest, "You do not own this item", nil))
-		return
-	}
-
-	err := h.itemService.UpdateItem(item)
+	err := h.itemService.UpdateItem(actor, item)
 	if err != nil {
 		e := apperrors.GetAppError(err, "Update item failed!")
-		c.JSON(http.StatusBadRequest, api.NewResponse(http.StatusBadRequest, "Update item failed!", e.Error()))
+		c.JSON(e.Status(), api.NewResponse(e.Status(), "Update item failed!", e.Error()))
 		return
 	}
 
@@ -218,11 +375,18 @@ func (h *ItemHandler) DeleteItem(c *gin.Context) {
 		return
 	}
 
+	userDetails, _ := c.Get("id")
+	if userDetails == nil {
+		c.JSON(http.StatusInternalServerError, api.NewResponse(http.StatusInternalServerError, "User not authenticated", nil))
+		return
+	}
+
+	actor := userDetails.(*middleware.User).Actor()
 
-	err = h.itemService.DeleteItem(itemId)
+	err = h.itemService.DeleteItem(actor, itemId)
 	if err != nil {
 		e := apperrors.GetAppError(err, "Item delete failed!")
-		c.JSON(http.StatusBadRequest, api.NewResponse(http.StatusBadRequest, "Item delete failed!", e.Error()))
+		c.JSON(e.Status(), api.NewResponse(e.Status(), "Item delete failed!", e.Error()))
 		return
 	}
 
@@ -231,9 +395,6 @@ func (h *ItemHandler) DeleteItem(c *gin.Context) {
 
 
 func (h *ItemHandler) GetItemsByOwnerId(c *gin.Context) {
-	limit := c.Query("limit")
-	page := c.Query("page")
-
 	userDetails, _ := c.Get("id")
 	if userDetails == nil {
 		c.JSON(http.StatusInternalServerError, api.NewResponse(http.StatusInternalServerError, "User not authenticated", nil))
@@ -241,10 +402,8 @@ func (h *ItemHandler) GetItemsByOwnerId(c *gin.Context) {
 	}
 
 	userId := userDetails.(*middleware.User).ID
-	limitValue, _ := strconv.Atoi(limit)
-	pageValue, _ := strconv.Atoi(page)
 
-	items, err := h.itemService.GetItemsByOwnerId(userId, limitValue, pageValue)
+	items, page, err := h.itemService.GetItemsByOwnerId(userId, api.PageRequest(c))
 
 	if err != nil {
 		e := apperrors.NewInternal()
@@ -252,7 +411,7 @@ func (h *ItemHandler) GetItemsByOwnerId(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, api.NewResponse(http.StatusOK, "Successful", items))
+	c.JSON(http.StatusOK, api.NewPagedResponse(http.StatusOK, "Successful", items, page))
 }
 
 
@@ -304,24 +463,24 @@ func (h *ItemHandler) BuyItem(c *gin.Context) {
 func (h *ItemHandler) UploadFile(c *gin.Context){
 	itemIdParam := c.Param("id")
 	itemId, _ := strconv.Atoi(itemIdParam)
-	
 
-	_, err := h.itemService.GetItemById(itemId)
-	if err != nil {
-		c.JSON(http.StatusNotFound, api.NewResponse(http.StatusNotFound, "Item not found", nil))
+	userDetails, _ := c.Get("id")
+	if userDetails == nil {
+		c.JSON(http.StatusInternalServerError, api.NewResponse(http.StatusInternalServerError, "User not authenticated", nil))
 		return
 	}
-	file, err := c.FormFile("file")
-	if err != nil {
-		c.JSON(http.StatusBadRequest, api.NewResponse(http.StatusBadRequest, "No file uploaded", nil))
+
+	actor := userDetails.(*middleware.User).Actor()
+
+	file, ok := imageFormFile(c)
+	if !ok {
 		return
 	}
 
-	uploadDir := "./uploads"
-
-	filePath, err := h.Utils.UploadItemFile(itemId, file, uploadDir)
+	filePath, err := h.itemService.UploadImage(actor, itemId, file)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, api.NewResponse(http.StatusInternalServerError, "File not upoaded", nil))
+		e := apperrors.GetAppError(err, "File not upoaded")
+		c.JSON(e.Status(), api.NewResponse(e.Status(), "File not upoaded", e.Error()))
 		return
 	}
 
@@ -348,12 +507,20 @@ func (h *ItemHandler) UpdateCategory(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, api.NewResponse(http.StatusBadRequest, "Invalid or category name", nil))
 		return
 	}
-	
 
-	err := h.itemService.UpdateCategory(int(itemId), categoryName)
+	userDetails, _ := c.Get("id")
+	if userDetails == nil {
+		c.JSON(http.StatusInternalServerError, api.NewResponse(http.StatusInternalServerError, "User not authenticated", nil))
+		return
+	}
+
+	actor := userDetails.(*middleware.User).Actor()
+
+	err := h.itemService.UpdateCategory(actor, int(itemId), categoryName)
 
 	if err != nil {
-		c.JSON(http.StatusBadRequest, api.NewResponse(http.StatusBadRequest, "Unable to update category", nil))
+		e := apperrors.GetAppError(err, "Unable to update category")
+		c.JSON(e.Status(), api.NewResponse(e.Status(), "Unable to update category", e.Error()))
 		return
 	}
 
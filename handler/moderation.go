@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"swap/api"
+	"swap/apperrors"
+	"swap/middleware"
+	"swap/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+
+type ModerationHandler struct {
+	moderationService models.IModerationService
+}
+
+
+func NewModerationHandler(ModerationService models.IModerationService) *ModerationHandler {
+	h := &ModerationHandler{ moderationService: ModerationService }
+	return h
+}
+
+
+func (h *ModerationHandler) GetFlags(c *gin.Context) {
+	status := strings.ToUpper(c.Query("status"))
+
+	flags, page, err := h.moderationService.GetFlags(status, api.PageRequest(c))
+	if err != nil {
+		e := apperrors.GetAppError(err, "Couldnt get flagged listings")
+		c.JSON(e.Status(), api.NewResponse(e.Status(), "Couldnt get flagged listings", gin.H{ "error" : e, }))
+		return
+	}
+
+	c.JSON(http.StatusOK, api.NewPagedResponse(http.StatusOK, "Successful", flags, page))
+}
+
+
+func (h *ModerationHandler) ReviewFlag(c *gin.Context) {
+	flagId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		ToFieldErrorResponse(c, "id", "Invalid flag ID")
+		return
+	}
+
+	var request api.ReviewFlagPayload
+	if ok := api.BindData(c, &request); !ok {
+		return
+	}
+
+	request.Sanitize()
+
+	userDetails, _ := c.Get("id")
+	if userDetails == nil {
+		c.JSON(http.StatusUnauthorized, api.NewResponse(http.StatusUnauthorized, "User not authenticated", nil))
+		return
+	}
+	reviewerId := userDetails.(*middleware.User).ID
+
+	flag, err := h.moderationService.ReviewFlag(reviewerId, uint(flagId), request.Status)
+	if err != nil {
+		e := apperrors.GetAppError(err, "Unable to review flag")
+		c.JSON(e.Status(), api.NewResponse(e.Status(), "Unable to review flag", gin.H{ "error" : e, }))
+		return
+	}
+
+	c.JSON(http.StatusOK, api.NewResponse(http.StatusOK, "Successful", flag))
+}
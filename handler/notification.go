@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"net/http"
+	"swap/api"
+	"swap/apperrors"
+	"swap/middleware"
+	"swap/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+
+type NotificationHandler struct {
+	notificationService models.INotificationService
+}
+
+
+func NewNotificationHandler(NotificationService models.INotificationService) *NotificationHandler {
+	h := &NotificationHandler{ notificationService: NotificationService }
+	return h
+}
+
+
+func (h *NotificationHandler) GetNotifications(c *gin.Context) {
+	userDetails, _ := c.Get("id")
+	if userDetails == nil {
+		c.JSON(http.StatusUnauthorized, api.NewResponse(http.StatusUnauthorized, "User not authenticated", nil))
+		return
+	}
+	userId := userDetails.(*middleware.User).ID
+	unreadOnly := c.Query("unread") == "true"
+
+	notifications, page, err := h.notificationService.GetNotifications(userId, unreadOnly, api.PageRequest(c))
+	if err != nil {
+		e := apperrors.GetAppError(err, "Couldnt get notifications")
+		c.JSON(e.Status(), api.NewResponse(e.Status(), "Couldnt get notifications", gin.H{ "error" : e, }))
+		return
+	}
+
+	c.JSON(http.StatusOK, api.NewPagedResponse(http.StatusOK, "Successful", notifications, page))
+}
+
+
+func (h *NotificationHandler) MarkRead(c *gin.Context) {
+	var request api.MarkNotificationsReadPayload
+	if ok := api.BindData(c, &request); !ok {
+		return
+	}
+
+	userDetails, _ := c.Get("id")
+	if userDetails == nil {
+		c.JSON(http.StatusUnauthorized, api.NewResponse(http.StatusUnauthorized, "User not authenticated", nil))
+		return
+	}
+	userId := userDetails.(*middleware.User).ID
+
+	if err := h.notificationService.MarkRead(userId, request.Ids); err != nil {
+		e := apperrors.GetAppError(err, "Unable to mark notifications as read")
+		c.JSON(e.Status(), api.NewResponse(e.Status(), "Unable to mark notifications as read", gin.H{ "error" : e, }))
+		return
+	}
+
+	c.JSON(http.StatusOK, api.NewResponse(http.StatusOK, "Successful", nil))
+}
@@ -0,0 +1,156 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"swap/api"
+	"swap/apperrors"
+	"swap/middleware"
+	"swap/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+
+type QuestionHandler struct {
+	questionService models.IQuestionService
+}
+
+
+func NewQuestionHandler(QuestionService models.IQuestionService) *QuestionHandler {
+	h := &QuestionHandler{ questionService: QuestionService }
+	return h
+}
+
+
+func (h *QuestionHandler) AskQuestion(c *gin.Context) {
+	itemId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		ToFieldErrorResponse(c, "id", "Invalid item ID")
+		return
+	}
+
+	var request api.QuestionPayload
+	if ok := api.BindData(c, &request); !ok {
+		return
+	}
+
+	request.Sanitize()
+	if request.Body == "" {
+		ToFieldErrorResponse(c, "body", "cannot be blank")
+		return
+	}
+
+	userDetails, _ := c.Get("id")
+	if userDetails == nil {
+		c.JSON(http.StatusUnauthorized, api.NewResponse(http.StatusUnauthorized, "User not authenticated", nil))
+		return
+	}
+	userId := userDetails.(*middleware.User).ID
+
+	question, err := h.questionService.AskQuestion(userId, uint(itemId), request.Body)
+	if err != nil {
+		e := apperrors.GetAppError(err, "Unable to ask question")
+		c.JSON(e.Status(), api.NewResponse(e.Status(), "Unable to ask question", gin.H{ "error" : e, }))
+		return
+	}
+
+	c.JSON(http.StatusCreated, api.NewResponse(http.StatusCreated, "Successful", question))
+}
+
+
+func (h *QuestionHandler) GetQuestions(c *gin.Context) {
+	itemId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		ToFieldErrorResponse(c, "id", "Invalid item ID")
+		return
+	}
+
+	userDetails, _ := c.Get("id")
+	if userDetails == nil {
+		c.JSON(http.StatusUnauthorized, api.NewResponse(http.StatusUnauthorized, "User not authenticated", nil))
+		return
+	}
+	actor := userDetails.(*middleware.User).Actor()
+
+	questions, page, err := h.questionService.GetQuestions(actor, uint(itemId), api.PageRequest(c))
+	if err != nil {
+		e := apperrors.GetAppError(err, "Couldnt get questions")
+		c.JSON(e.Status(), api.NewResponse(e.Status(), "Couldnt get questions", gin.H{ "error" : e, }))
+		return
+	}
+
+	c.JSON(http.StatusOK, api.NewPagedResponse(http.StatusOK, "Successful", questions, page))
+}
+
+
+func (h *QuestionHandler) AnswerQuestion(c *gin.Context) {
+	itemId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		ToFieldErrorResponse(c, "id", "Invalid item ID")
+		return
+	}
+
+	questionId, err := strconv.Atoi(c.Param("questionId"))
+	if err != nil {
+		ToFieldErrorResponse(c, "questionId", "Invalid question ID")
+		return
+	}
+
+	var request api.AnswerPayload
+	if ok := api.BindData(c, &request); !ok {
+		return
+	}
+
+	request.Sanitize()
+	if request.Answer == "" {
+		ToFieldErrorResponse(c, "answer", "cannot be blank")
+		return
+	}
+
+	userDetails, _ := c.Get("id")
+	if userDetails == nil {
+		c.JSON(http.StatusUnauthorized, api.NewResponse(http.StatusUnauthorized, "User not authenticated", nil))
+		return
+	}
+	actor := userDetails.(*middleware.User).Actor()
+
+	question, err := h.questionService.AnswerQuestion(actor, uint(itemId), uint(questionId), request.Answer)
+	if err != nil {
+		e := apperrors.GetAppError(err, "Unable to answer question")
+		c.JSON(e.Status(), api.NewResponse(e.Status(), "Unable to answer question", gin.H{ "error" : e, }))
+		return
+	}
+
+	c.JSON(http.StatusOK, api.NewResponse(http.StatusOK, "Successful", question))
+}
+
+
+func (h *QuestionHandler) DeleteQuestion(c *gin.Context) {
+	itemId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		ToFieldErrorResponse(c, "id", "Invalid item ID")
+		return
+	}
+
+	questionId, err := strconv.Atoi(c.Param("questionId"))
+	if err != nil {
+		ToFieldErrorResponse(c, "questionId", "Invalid question ID")
+		return
+	}
+
+	userDetails, _ := c.Get("id")
+	if userDetails == nil {
+		c.JSON(http.StatusUnauthorized, api.NewResponse(http.StatusUnauthorized, "User not authenticated", nil))
+		return
+	}
+	actor := userDetails.(*middleware.User).Actor()
+
+	if err := h.questionService.DeleteQuestion(actor, uint(itemId), uint(questionId)); err != nil {
+		e := apperrors.GetAppError(err, "Unable to delete question")
+		c.JSON(e.Status(), api.NewResponse(e.Status(), "Unable to delete question", gin.H{ "error" : e, }))
+		return
+	}
+
+	c.JSON(http.StatusOK, api.NewResponse(http.StatusOK, "Question deleted", nil))
+}
@@ -0,0 +1,43 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"swap/api"
+	"swap/apperrors"
+	"swap/middleware"
+	"swap/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+
+type RecommendationHandler struct {
+	recommendationService models.IRecommendationService
+}
+
+
+func NewRecommendationHandler(RecommendationService models.IRecommendationService) *RecommendationHandler {
+	h := &RecommendationHandler{ recommendationService: RecommendationService }
+	return h
+}
+
+
+func (h *RecommendationHandler) GetRecommendations(c *gin.Context) {
+	userDetails, _ := c.Get("id")
+	if userDetails == nil {
+		c.JSON(http.StatusUnauthorized, api.NewResponse(http.StatusUnauthorized, "User not authenticated", nil))
+		return
+	}
+	userId := userDetails.(*middleware.User).ID
+	limit, _ := strconv.Atoi(c.Query("limit"))
+
+	recommendations, err := h.recommendationService.GetRecommendations(userId, limit)
+	if err != nil {
+		e := apperrors.GetAppError(err, "Couldnt get recommendations")
+		c.JSON(e.Status(), api.NewResponse(e.Status(), "Couldnt get recommendations", gin.H{ "error" : e, }))
+		return
+	}
+
+	c.JSON(http.StatusOK, api.NewResponse(http.StatusOK, "Successful", recommendations))
+}
@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"swap/api"
+	"swap/apperrors"
+	"swap/middleware"
+	"swap/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+
+type SavedSearchHandler struct {
+	savedSearchService models.ISavedSearchService
+}
+
+
+func NewSavedSearchHandler(SavedSearchService models.ISavedSearchService) *SavedSearchHandler {
+	h := &SavedSearchHandler{ savedSearchService: SavedSearchService }
+	return h
+}
+
+
+func (h *SavedSearchHandler) CreateSavedSearch(c *gin.Context) {
+	var request api.SavedSearchPayload
+	if ok := api.BindData(c, &request); !ok {
+		return
+	}
+
+	request.Sanitize()
+
+	userDetails, _ := c.Get("id")
+	if userDetails == nil {
+		log.Print("User not authenticated")
+		c.JSON(http.StatusUnauthorized, api.NewResponse(http.StatusUnauthorized, "User not authenticated", nil))
+		return
+	}
+	userId := userDetails.(*middleware.User).ID
+
+	search, err := h.savedSearchService.CreateSavedSearch(request.ToEntity(userId))
+	if err != nil {
+		e := apperrors.GetAppError(err, "Unable to save search")
+		c.JSON(e.Status(), api.NewResponse(e.Status(), "Unable to save search", gin.H{ "error" : e, }))
+		return
+	}
+
+	c.JSON(http.StatusCreated, api.NewResponse(http.StatusCreated, "Successful", search))
+}
+
+
+func (h *SavedSearchHandler) GetSavedSearches(c *gin.Context) {
+	userDetails, _ := c.Get("id")
+	if userDetails == nil {
+		c.JSON(http.StatusUnauthorized, api.NewResponse(http.StatusUnauthorized, "User not authenticated", nil))
+		return
+	}
+	userId := userDetails.(*middleware.User).ID
+
+	searches, page, err := h.savedSearchService.GetSavedSearches(userId, api.PageRequest(c))
+	if err != nil {
+		e := apperrors.GetAppError(err, "Couldnt get saved searches")
+		c.JSON(e.Status(), api.NewResponse(e.Status(), "Couldnt get saved searches", gin.H{ "error" : e, }))
+		return
+	}
+
+	c.JSON(http.StatusOK, api.NewPagedResponse(http.StatusOK, "Successful", searches, page))
+}
+
+
+func (h *SavedSearchHandler) DeleteSavedSearch(c *gin.Context) {
+	searchId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		ToFieldErrorResponse(c, "id", "Invalid saved search ID")
+		return
+	}
+
+	userDetails, _ := c.Get("id")
+	if userDetails == nil {
+		c.JSON(http.StatusUnauthorized, api.NewResponse(http.StatusUnauthorized, "User not authenticated", nil))
+		return
+	}
+	userId := userDetails.(*middleware.User).ID
+
+	if err := h.savedSearchService.DeleteSavedSearch(userId, uint(searchId)); err != nil {
+		e := apperrors.GetAppError(err, "Unable to delete saved search")
+		c.JSON(e.Status(), api.NewResponse(e.Status(), "Unable to delete saved search", gin.H{ "error" : e, }))
+		return
+	}
+
+	c.JSON(http.StatusOK, api.NewResponse(http.StatusOK, "Successfully deleted saved search", nil))
+}
@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"swap/api"
+	"swap/apperrors"
+	"swap/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+
+type SuggestionHandler struct {
+	suggestionService models.ISuggestionService
+}
+
+
+func NewSuggestionHandler(SuggestionService models.ISuggestionService) *SuggestionHandler {
+	h := &SuggestionHandler{ suggestionService: SuggestionService }
+	return h
+}
+
+
+func (h *SuggestionHandler) SuggestItemNames(c *gin.Context) {
+	h.respond(c, h.suggestionService.SuggestItemNames)
+}
+
+
+func (h *SuggestionHandler) SuggestCategories(c *gin.Context) {
+	h.respond(c, h.suggestionService.SuggestCategories)
+}
+
+
+func (h *SuggestionHandler) SuggestUserNames(c *gin.Context) {
+	h.respond(c, h.suggestionService.SuggestUserNames)
+}
+
+
+// respond looks up suggestions for the q and limit query parameters
+func (h *SuggestionHandler) respond(c *gin.Context, lookup func(prefix string, limit int) ([]models.Suggestion, error)) {
+	limit, _ := strconv.Atoi(c.Query("limit"))
+
+	suggestions, err := lookup(c.Query("q"), limit)
+	if err != nil {
+		e := apperrors.GetAppError(err, "Couldnt get suggestions")
+		c.JSON(e.Status(), api.NewResponse(e.Status(), "Couldnt get suggestions", gin.H{ "error" : e, }))
+		return
+	}
+
+	c.JSON(http.StatusOK, api.NewResponse(http.StatusOK, "Successful", suggestions))
+}
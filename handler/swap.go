@@ -57,12 +57,6 @@ func (h *SwapHandler) InitiateSwapRequest(c *gin.Context) {
 
 
 func (h *SwapHandler) GetPendingSwapRequests(c *gin.Context) {
-	limit := c.Query("limit")
-	page := c.Query("page")
-
-	limitValue, _ := strconv.Atoi(limit)
-	pageValue, _ := strconv.Atoi(page)
-
 	userDetails, _ := c.Get("id")
 
 	if userDetails == nil {
@@ -71,14 +65,14 @@ func (h *SwapHandler) GetPendingSwapRequests(c *gin.Context) {
 	}
 	ownerId := userDetails.(*middleware.User).ID
 
-	swapRequests, err := h.swapService.GetPendingSwapRequests(int(ownerId), limitValue, pageValue)
+	swapRequests, page, err := h.swapService.GetPendingSwapRequests(int(ownerId), api.PageRequest(c))
 
 	if err != nil {
 		c.JSON(http.StatusBadRequest, api.NewResponse(http.StatusBadRequest, "Could not get pending swap requests", nil))
 		return
 	}
 	
-	c.JSON(http.StatusOK, api.NewResponse(http.StatusOK, "Successful", swapRequests))
+	c.JSON(http.StatusOK, api.NewPagedResponse(http.StatusOK, "Successful", swapRequests, page))
 }
 
 
@@ -93,12 +87,13 @@ func (h *SwapHandler) RejectSwapRequest(c *gin.Context) {
 		return
 	}
 
-	ownerId := int(userDetails.(*middleware.User).ID)
+	actor := userDetails.(*middleware.User).Actor()
 
-	err := h.swapService.RejectSwapRequest(ownerId, swapId)
+	err := h.swapService.RejectSwapRequest(actor, swapId)
 
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, api.NewResponse(http.StatusInternalServerError, "Could not reject swap request", nil))
+		e := apperrors.GetAppError(err, "Could not reject swap request")
+		c.JSON(e.Status(), api.NewResponse(e.Status(), "Could not reject swap request", e.Error()))
 		return
 	}
 
@@ -117,12 +112,13 @@ func (h *SwapHandler) AcceptSwapRequest(c *gin.Context) {
 		return
 	}
 
-	ownerId := int(userDetails.(*middleware.User).ID)
+	actor := userDetails.(*middleware.User).Actor()
 
-	result, err := h.swapService.AcceptSwapRequest(ownerId, swapId)
+	result, err := h.swapService.AcceptSwapRequest(actor, swapId)
 
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, api.NewResponse(http.StatusInternalServerError, "Could not accept swap request", nil))
+		e := apperrors.GetAppError(err, "Could not accept swap request")
+		c.JSON(e.Status(), api.NewResponse(e.Status(), "Could not accept swap request", e.Error()))
 		return
 	}
 
@@ -142,7 +138,7 @@ func (h *SwapHandler) CompleteSwapRequest(c *gin.Context) {
 		return
 	}
 
-	ownerId := userDetails.(*middleware.User).ID
+	actor := userDetails.(*middleware.User).Actor()
 
 
 	var request map[string] interface{}
@@ -159,10 +155,11 @@ func (h *SwapHandler) CompleteSwapRequest(c *gin.Context) {
 		return
 	}
 
-	result, err := h.swapService.CompleteSwapRequest(ownerId, amount, uint(swapId))
+	result, err := h.swapService.CompleteSwapRequest(actor, amount, uint(swapId))
 
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, api.NewResponse(http.StatusInternalServerError, "Could not complete swap request", nil))
+		e := apperrors.GetAppError(err, "Could not complete swap request")
+		c.JSON(e.Status(), api.NewResponse(e.Status(), "Could not complete swap request", e.Error()))
 		return
 	}
 
@@ -206,12 +203,6 @@ func (h *SwapHandler) GetIncompleteSwapByInitiatorId(c *gin.Context) {
 
 
 func (h *SwapHandler) GetAllIncompleteSwapByOwnerId(c *gin.Context) {
-	limit := c.Param("limit")
-	page := c.Param("page")
-
-	limitValue, _ := strconv.Atoi(limit)
-	pageValue, _ := strconv.Atoi(page)
-
 	userDetails, _ := c.Get("id")
 
 	if userDetails == nil {
@@ -221,12 +212,12 @@ func (h *SwapHandler) GetAllIncompleteSwapByOwnerId(c *gin.Context) {
 
 	ownerId := int(userDetails.(*middleware.User).ID)
 
-	swapRequests, err := h.swapService.GetAllIncompleteSwapByOwnerId(ownerId, limitValue, pageValue)
+	swapRequests, page, err := h.swapService.GetAllIncompleteSwapByOwnerId(ownerId, api.PageRequest(c))
 
 	if err != nil {
 		c.JSON(http.StatusBadRequest, api.NewResponse(http.StatusInternalServerError, "Could not get incomplete swaps", nil))
 		return
 	}
 
-	c.JSON(http.StatusOK, api.NewResponse(http.StatusOK, "Successful", swapRequests))
+	c.JSON(http.StatusOK, api.NewPagedResponse(http.StatusOK, "Successful", swapRequests, page))
 }
\ No newline at end of file
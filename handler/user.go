@@ -18,12 +18,13 @@ import (
 
 type UserHandler struct {
 	userService models.IUserService
+	blobStore   models.IBlobStore
 }
 
 
-func NewUserHandler(UserService models.IUserService) *UserHandler {
+func NewUserHandler(UserService models.IUserService, BlobStore models.IBlobStore) *UserHandler {
 	// Create a handler (which will later have injected services)
-	h := &UserHandler{userService : UserService}
+	h := &UserHandler{userService : UserService, blobStore : BlobStore}
 	return h
 }
 
@@ -144,18 +145,12 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 
 
 func (h *UserHandler) Search(c *gin.Context) {
-	limit := c.Query("limit")
-	page := c.Query("page")
-
 	var request api.UserSearchRequest
 	if ok := api.BindData(c, &request); !ok {
 		return
 	}
 
-	limitValue, _ := strconv.Atoi(limit)
-	pageValue, _ := strconv.Atoi(page)
-
-	users, err := h.userService.Search(request.SearchTerm, limitValue, pageValue)
+	users, page, err := h.userService.Search(request.SearchTerm, api.PageRequest(c))
 	if err != nil {
 		e := apperrors.NewInternal()
 
@@ -175,7 +170,7 @@ func (h *UserHandler) Search(c *gin.Context) {
 		searchResponses = append(searchResponses, searchResponse)
 	}
 
-	c.JSON(http.StatusOK, api.NewResponse(http.StatusOK, "Successful", searchResponses))
+	c.JSON(http.StatusOK, api.NewPagedResponse(http.StatusOK, "Successful", searchResponses, page))
 }
 
 
@@ -262,17 +257,15 @@ func (h *UserHandler) ConfirmUserPassword(c *gin.Context) {
 // }
 
 func (h *UserHandler) UploadFile(c *gin.Context){
-	file, err := c.FormFile("file")
-	if err != nil {
-		c.JSON(http.StatusBadRequest, api.NewResponse(http.StatusBadRequest, "No file uploaded", nil))
+	file, ok := imageFormFile(c)
+	if !ok {
 		return
 	}
 
-	uploadDir := "./uploads"
-
-	filePath, err := utils.UploadFile(file, uploadDir)
+	filePath, err := utils.UploadFile(h.blobStore, file, "")
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, api.NewResponse(http.StatusInternalServerError, "File not upoaded", nil))
+		e := apperrors.GetAppError(err, "File not upoaded")
+		c.JSON(e.Status(), api.NewResponse(e.Status(), "File not upoaded", gin.H{ "error" : e, }))
 		return
 	}
 
@@ -416,18 +409,14 @@ func (h *UserHandler) FindUserByPhoneNumber(c *gin.Context) {
 
 func (h *UserHandler) GetUserTransactions(c *gin.Context) {
 	userDetails, _ := c.Get("id")
-	limit := c.Query("limit")
-	page := c.Query("page")
 
 	if userDetails == nil {
 		c.JSON(http.StatusInternalServerError, api.NewResponse(http.StatusInternalServerError, "Error getting user details", nil))
 		return
 	}
 	userId := int(userDetails.(*middleware.User).ID)
-	limitValue, _ := strconv.Atoi(limit)
-	pageValue, _ := strconv.Atoi(page)
 
-	transactions, err := h.userService.GetUserTransactions(userId, limitValue, pageValue)
+	transactions, page, err := h.userService.GetUserTransactions(userId, api.PageRequest(c))
 
 	if transactions == nil {
 		log.Print("No transaction available")
@@ -442,7 +431,7 @@ func (h *UserHandler) GetUserTransactions(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, api.NewResponse(http.StatusOK, "Successful", transactions))
+	c.JSON(http.StatusOK, api.NewPagedResponse(http.StatusOK, "Successful", transactions, page))
 }
 
 
@@ -458,4 +447,30 @@ func (h *UserHandler) GetUserByItemId(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, api.NewResponse(http.StatusOK, "Successful", user))
-}
\ No newline at end of file
+}
+
+
+func (h *UserHandler) UpdateUserRole(c *gin.Context) {
+	routeId := c.Param("id")
+
+	userId, err := strconv.Atoi(routeId)
+	if err != nil {
+		e := apperrors.NewBadRequest("invalid user id provided")
+		c.JSON(e.Status(), e)
+		return
+	}
+
+	var request api.UpdateRolePayload
+	if ok := api.BindData(c, &request); !ok {
+		return
+	}
+
+	err = h.userService.UpdateUserRole(userId, request.Role)
+	if err != nil {
+		e := apperrors.GetAppError(err, "Unable to update user role")
+		c.JSON(e.Status(), api.NewResponse(e.Status(), e.Error(), nil))
+		return
+	}
+
+	c.JSON(http.StatusOK, api.NewResponse(http.StatusOK, "Successful", nil))
+}
@@ -1,13 +1,43 @@
 package handler
 
 import (
+	"mime/multipart"
 	"net/http"
 	"swap/api"
 	"swap/apperrors"
+	"swap/models"
 
 	"github.com/gin-gonic/gin"
 )
 
+// dateLayout is the format of dates in query parameters
+const dateLayout = "2006-01-02"
+
+
+// maxImageRequestSize caps an image upload request, leaving room for the
+// multipart framing around a file of the largest allowed size
+const maxImageRequestSize = models.MaxImageUploadSize + 64<<10
+
+
+// imageFormFile reads the uploaded file field of a size-capped request,
+// responding with 413 when the request is over the cap
+func imageFormFile(c *gin.Context) (*multipart.FileHeader, bool) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImageRequestSize)
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		if c.Request.ContentLength > maxImageRequestSize {
+			e := apperrors.NewPayloadTooLarge(models.MaxImageUploadSize, c.Request.ContentLength)
+			c.JSON(e.Status(), api.NewResponse(e.Status(), "File not uploaded", gin.H{ "error" : e, }))
+			return nil, false
+		}
+		c.JSON(http.StatusBadRequest, api.NewResponse(http.StatusBadRequest, "No file uploaded", nil))
+		return nil, false
+	}
+	return file, true
+}
+
+
 func ToFieldErrorResponse(c *gin.Context, field, message string) {
 
 	c.JSON(http.StatusBadRequest, api.NewResponse(http.StatusBadRequest, "Bad Request", gin.H{
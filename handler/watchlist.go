@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"swap/api"
+	"swap/apperrors"
+	"swap/middleware"
+	"swap/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+
+type WatchlistHandler struct {
+	watchlistService models.IWatchlistService
+}
+
+
+func NewWatchlistHandler(WatchlistService models.IWatchlistService) *WatchlistHandler {
+	h := &WatchlistHandler{ watchlistService: WatchlistService }
+	return h
+}
+
+
+func (h *WatchlistHandler) WatchItem(c *gin.Context) {
+	itemId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		ToFieldErrorResponse(c, "id", "Invalid item ID")
+		return
+	}
+
+	userDetails, _ := c.Get("id")
+	if userDetails == nil {
+		c.JSON(http.StatusUnauthorized, api.NewResponse(http.StatusUnauthorized, "User not authenticated", nil))
+		return
+	}
+	userId := userDetails.(*middleware.User).ID
+
+	if err := h.watchlistService.WatchItem(userId, uint(itemId)); err != nil {
+		e := apperrors.GetAppError(err, "Unable to watch item")
+		c.JSON(e.Status(), api.NewResponse(e.Status(), "Unable to watch item", gin.H{ "error" : e, }))
+		return
+	}
+
+	c.JSON(http.StatusOK, api.NewResponse(http.StatusOK, "Item added to watchlist", nil))
+}
+
+
+func (h *WatchlistHandler) UnwatchItem(c *gin.Context) {
+	itemId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		ToFieldErrorResponse(c, "id", "Invalid item ID")
+		return
+	}
+
+	userDetails, _ := c.Get("id")
+	if userDetails == nil {
+		c.JSON(http.StatusUnauthorized, api.NewResponse(http.StatusUnauthorized, "User not authenticated", nil))
+		return
+	}
+	userId := userDetails.(*middleware.User).ID
+
+	if err := h.watchlistService.UnwatchItem(userId, uint(itemId)); err != nil {
+		e := apperrors.GetAppError(err, "Unable to unwatch item")
+		c.JSON(e.Status(), api.NewResponse(e.Status(), "Unable to unwatch item", gin.H{ "error" : e, }))
+		return
+	}
+
+	c.JSON(http.StatusOK, api.NewResponse(http.StatusOK, "Item removed from watchlist", nil))
+}
+
+
+func (h *WatchlistHandler) GetWatchedItems(c *gin.Context) {
+	userDetails, _ := c.Get("id")
+	if userDetails == nil {
+		c.JSON(http.StatusUnauthorized, api.NewResponse(http.StatusUnauthorized, "User not authenticated", nil))
+		return
+	}
+	userId := userDetails.(*middleware.User).ID
+
+	items, page, err := h.watchlistService.GetWatchedItems(userId, api.PageRequest(c))
+	if err != nil {
+		e := apperrors.GetAppError(err, "Couldnt get watchlist")
+		c.JSON(e.Status(), api.NewResponse(e.Status(), "Couldnt get watchlist", gin.H{ "error" : e, }))
+		return
+	}
+
+	c.JSON(http.StatusOK, api.NewPagedResponse(http.StatusOK, "Successful", items, page))
+}
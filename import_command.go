@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"swap/api"
+	"swap/models"
+)
+
+
+// runImportCommand bulk imports items from a CSV or JSON Lines file:
+//
+//	swap import -owner <user ID> [-format csv|jsonl] [-dry-run] <file>
+//
+// It prints the import report as JSON and returns the process exit code.
+func runImportCommand(itemService models.IItemService, args []string) int {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	owner := flags.Uint("owner", 0, "ID of the user who will own the imported items")
+	format := flags.String("format", "", "file format, csv or jsonl (default: from the file extension)")
+	dryRun := flags.Bool("dry-run", false, "validate the file without saving anything")
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *owner == 0 || flags.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: swap import -owner <user ID> [-format csv|jsonl] [-dry-run] <file>")
+		return 2
+	}
+
+	path := flags.Arg(0)
+	if *format == "" {
+		*format = strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "could not open %s: %v\n", path, err)
+		return 1
+	}
+	defer file.Close()
+
+	rows, err := api.ParseItemImport(file, *format)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "could not parse %s: %v\n", path, err)
+		return 1
+	}
+
+	report, err := itemService.ImportItems(*owner, rows, *dryRun)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "import failed: %v\n", err)
+		return 1
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(report)
+
+	if report.Failed > 0 {
+		return 1
+	}
+	return 0
+}
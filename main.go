@@ -4,8 +4,11 @@ import (
 	"log"
 	"os"
 	"net/http"
+	"strings"
+	"time"
 
 	"swap/middleware"
+	"swap/models"
 	"swap/repository"
 	services "swap/services"
 	utils "swap/utils"
@@ -45,20 +48,76 @@ func main() {
 	categoryRepository := repository.NewCategoryRepository(swapDB.DB)
 	swapRepository := repository.NewSwapRepository(swapDB.DB)
 	imageRepository := repository.NewImageRepository(swapDB.DB)
+	suggestionRepository := repository.NewSuggestionRepository(swapDB.DB)
+	notificationRepository := repository.NewNotificationRepository(swapDB.DB)
+	savedSearchRepository := repository.NewSavedSearchRepository(swapDB.DB)
+	watchlistRepository := repository.NewWatchlistRepository(swapDB.DB)
+	analyticsRepository := repository.NewAnalyticsRepository(swapDB.DB)
+	recommendationRepository := repository.NewRecommendationRepository(swapDB.DB)
+	claimRepository := repository.NewClaimRepository(swapDB.DB)
+	questionRepository := repository.NewQuestionRepository(swapDB.DB)
+	listingFlagRepository := repository.NewListingFlagRepository(swapDB.DB)
+
+	blobStore, err := utils.NewBlobStore(os.Getenv("BLOB_DRIVER"))
+	if err != nil {
+		log.Fatalf("Could not set up blob storage: %v\n", err)
+	}
+
+	util := utils.NewUtils(imageRepository, blobStore)
+	geocoder := utils.NewGeocoder(os.Getenv("GEOCODER"))
+	detector := services.NewDuplicateDetector(listingFlagRepository)
+	thumbnails := services.NewThumbnailService(imageRepository, blobStore, 2)
 
 	userService := services.NewUserService(userRepository)
-	itemService := services.NewItemService(itemRepository)
-	imageService := services.NewImageService(imageRepository)
+	notificationService := services.NewNotificationService(notificationRepository, userRepository)
+	savedSearchService := services.NewSavedSearchService(savedSearchRepository, notificationService)
+	watchlistService := services.NewWatchlistService(watchlistRepository, notificationService)
+	itemService := services.NewItemService(itemRepository, categoryRepository, userRepository, geocoder, detector, thumbnails, util,
+		savedSearchService, watchlistService)
+	analyticsService := services.NewAnalyticsService(analyticsRepository, itemRepository)
+	recommendationService := services.NewRecommendationService(recommendationRepository)
+	imageService := services.NewImageService(imageRepository, itemRepository, blobStore)
 	categoryService := services.NewCategoryService(categoryRepository)
-	swapService := services.NewSwapService(swapRepository)
-	util := utils.NewUtils(imageRepository)
-
-	userHandler := shandlers.NewUserHandler(userService)
-	itemHandler := shandlers.NewItemHandler(itemService, util)
+	swapService := services.NewSwapService(swapRepository, itemRepository, watchlistService)
+	suggestionService := services.NewSuggestionService(suggestionRepository)
+	claimService := services.NewClaimService(claimRepository, itemRepository, notificationService, watchlistService)
+	questionService := services.NewQuestionService(questionRepository, itemRepository, notificationService)
+	listingService := services.NewListingService(itemRepository, notificationService, watchlistService)
+	moderationService := services.NewModerationService(listingFlagRepository)
+
+	userHandler := shandlers.NewUserHandler(userService, blobStore)
+	itemHandler := shandlers.NewItemHandler(itemService, analyticsService, questionService)
 	imageHandler := shandlers.NewImageHandler(imageService)
 	categoryHandler := shandlers.NewCategoryHandler(categoryService)
 	swapHandler := shandlers.NewSwapHandler(swapService)
+	suggestionHandler := shandlers.NewSuggestionHandler(suggestionService)
+	savedSearchHandler := shandlers.NewSavedSearchHandler(savedSearchService)
+	notificationHandler := shandlers.NewNotificationHandler(notificationService)
+	watchlistHandler := shandlers.NewWatchlistHandler(watchlistService)
+	recommendationHandler := shandlers.NewRecommendationHandler(recommendationService)
+	claimHandler := shandlers.NewClaimHandler(claimService)
+	questionHandler := shandlers.NewQuestionHandler(questionService)
+	moderationHandler := shandlers.NewModerationHandler(moderationService)
+
+
+	// Bulk imports from the command line skip instant saved search alerts,
+	// which would be cut off on exit; daily digests still include the items
+	if len(os.Args) > 1 && os.Args[1] == "import" {
+		importService := services.NewItemService(itemRepository, categoryRepository, userRepository, geocoder, detector, thumbnails, util)
+		os.Exit(runImportCommand(importService, os.Args[2:]))
+	}
 
+	userService.BootstrapAdmins(strings.Split(os.Getenv("ADMIN_EMAILS"), ","))
+
+	// Daily digests are sent once their search has gone a day without one;
+	// checking hourly keeps them on time across restarts
+	utils.RunEvery("saved-search-digests", time.Hour, savedSearchService.SendDailyDigests)
+	utils.RunEvery("item-stats", time.Hour, analyticsService.AggregateDailyStats)
+	utils.RunEvery("listing-reminders", time.Hour, listingService.SendExpiryReminders)
+	utils.RunEvery("listing-expiry", 15*time.Minute, listingService.ExpireListings)
+	utils.RunOnce("image-variants", thumbnails.GenerateMissing)
+	utils.RunOnce("item-similarities", recommendationService.ComputeSimilarities)
+	utils.RunEvery("item-similarities", 6*time.Hour, recommendationService.ComputeSimilarities)
 
 	jwtMiddleware, err := middleware.Middleware(userService)
 
@@ -135,6 +194,7 @@ func main() {
 
 	itemGroup := ginEngine.Group("/api/items").Use(jwtMiddleware.MiddlewareFunc())
 	itemGroup.POST("/register", itemHandler.RegisterItem)
+	itemGroup.POST("/import", itemHandler.ImportItems)
 	itemGroup.PUT("/buy/:id", itemHandler.BuyItem)
 	// itemGroup.PUT("/swap", itemHandler.SwapItem)
 
@@ -142,21 +202,74 @@ func main() {
 	itemGroup.PUT("/:id", itemHandler.UpdateItem)
 	itemGroup.DELETE("/:id", itemHandler.DeleteItem)
 	itemGroup.GET("/self", itemHandler.GetItemsByOwnerId)
-	itemGroup.GET("/search", itemHandler.GetItemsByCategory)
-	itemGroup.GET("/search-unsold", itemHandler.GetUnsoldItemsByCategory)
+	itemGroup.GET("/watched", watchlistHandler.GetWatchedItems)
+	itemGroup.GET("/recommended", recommendationHandler.GetRecommendations)
+	itemGroup.PUT("/:id/watch", watchlistHandler.WatchItem)
+	itemGroup.DELETE("/:id/watch", watchlistHandler.UnwatchItem)
+	itemGroup.GET("/:id/analytics", itemHandler.GetItemAnalytics)
+	itemGroup.GET("/:id/revisions", itemHandler.GetItemRevisions)
+	itemGroup.PUT("/:id/relist", itemHandler.RelistItem)
+	itemGroup.POST("/:id/claims", claimHandler.CreateClaim)
+	itemGroup.DELETE("/:id/claims", claimHandler.WithdrawClaim)
+	itemGroup.GET("/:id/claims", claimHandler.GetClaims)
+	itemGroup.PUT("/:id/claims/complete", claimHandler.CompleteClaim)
+	itemGroup.PUT("/:id/claims/:claimId/choose", claimHandler.ChooseClaim)
+	itemGroup.POST("/:id/questions", questionHandler.AskQuestion)
+	itemGroup.GET("/:id/questions", questionHandler.GetQuestions)
+	itemGroup.PUT("/:id/questions/:questionId/answer", questionHandler.AnswerQuestion)
+	itemGroup.DELETE("/:id/questions/:questionId", questionHandler.DeleteQuestion)
+	itemGroup.POST("/search", itemHandler.QueryItems)
+	itemGroup.GET("/text-search", itemHandler.SearchItems)
 	itemGroup.POST("/upload/:id", itemHandler.UploadFile)
+	itemGroup.PUT("/:id/images/order", imageHandler.ReorderImages)
+	itemGroup.PUT("/:id/images/:imageId/cover", imageHandler.SetCoverImage)
+	itemGroup.DELETE("/:id/images/:imageId", imageHandler.DeleteImage)
 	itemGroup.PUT("/update", itemHandler.UpdateCategory)
 
 
 	categoryGroup := ginEngine.Group("api/categories").Use(jwtMiddleware.MiddlewareFunc())
-	categoryGroup.POST("/create", categoryHandler.CreateCategory)
-
-	categoryGroup.PUT("/ban", categoryHandler.BanCategory)
-	categoryGroup.PUT("/unban", categoryHandler.UnBanCategory)
 	categoryGroup.GET("/status", categoryHandler.CheckStatus)
 	categoryGroup.GET("/valid-categories", categoryHandler.GetAllValidCategories)
 	categoryGroup.GET("/:id", categoryHandler.GetAllItemsInCategory)
-	categoryGroup.DELETE("/delete", categoryHandler.DeleteCategory)
+
+	categoryAdminGroup := ginEngine.Group("api/categories").Use(jwtMiddleware.MiddlewareFunc(),
+		middleware.RequirePermissions(models.ManageCategories))
+	categoryAdminGroup.POST("/create", categoryHandler.CreateCategory)
+	categoryAdminGroup.PUT("/ban", categoryHandler.BanCategory)
+	categoryAdminGroup.PUT("/unban", categoryHandler.UnBanCategory)
+	categoryAdminGroup.DELETE("/delete", categoryHandler.DeleteCategory)
+	categoryAdminGroup.PUT("/:id/schema", categoryHandler.UpdateAttributeSchema)
+	categoryAdminGroup.PUT("/rename", categoryHandler.RenameCategory)
+	categoryAdminGroup.PUT("/merge", categoryHandler.MergeCategory)
+	categoryAdminGroup.PUT("/reassign", categoryHandler.ReassignItems)
+
+
+	moderationGroup := ginEngine.Group("api/moderation").Use(jwtMiddleware.MiddlewareFunc(),
+		middleware.RequirePermissions(models.ModerateListings))
+	moderationGroup.GET("/flags", moderationHandler.GetFlags)
+	moderationGroup.PUT("/flags/:id", moderationHandler.ReviewFlag)
+
+
+	adminGroup := ginEngine.Group("api/admin").Use(jwtMiddleware.MiddlewareFunc(),
+		middleware.RequirePermissions(models.ManageUsers))
+	adminGroup.PUT("/users/:id/role", userHandler.UpdateUserRole)
+
+
+	savedSearchGroup := ginEngine.Group("api/saved-searches").Use(jwtMiddleware.MiddlewareFunc())
+	savedSearchGroup.POST("", savedSearchHandler.CreateSavedSearch)
+	savedSearchGroup.GET("", savedSearchHandler.GetSavedSearches)
+	savedSearchGroup.DELETE("/:id", savedSearchHandler.DeleteSavedSearch)
+
+
+	notificationGroup := ginEngine.Group("api/notifications").Use(jwtMiddleware.MiddlewareFunc())
+	notificationGroup.GET("", notificationHandler.GetNotifications)
+	notificationGroup.PUT("/read", notificationHandler.MarkRead)
+
+
+	suggestGroup := ginEngine.Group("api/suggest").Use(jwtMiddleware.MiddlewareFunc())
+	suggestGroup.GET("/items", suggestionHandler.SuggestItemNames)
+	suggestGroup.GET("/categories", suggestionHandler.SuggestCategories)
+	suggestGroup.GET("/users", suggestionHandler.SuggestUserNames)
 
 
 	swapGroup := ginEngine.Group("api/swaps").Use(jwtMiddleware.MiddlewareFunc())
@@ -170,10 +283,15 @@ func main() {
 	swapGroup.DELETE("/complete/:id", swapHandler.CompleteSwapRequest)
 	swapGroup.DELETE("/reject/:id", swapHandler.RejectSwapRequest)
 
-	ginEngine.GET("/read-image", imageHandler.ReadImage)
+	ginEngine.GET("/images/:id", imageHandler.ServeImage)
 	ginEngine.GET("/read-image/:id", imageHandler.ReadFirstImageById)
 	ginEngine.GET("/read-all-image/:id", imageHandler.ReadAllImagesByItemId)
-	ginEngine.GET("/read-image-path", imageHandler.ReadImageByPath)
+
+	// Stores that cannot serve signed URLs themselves have them served here
+	if verifier, ok := blobStore.(models.IBlobURLVerifier); ok {
+		blobHandler := shandlers.NewBlobHandler(blobStore, verifier)
+		ginEngine.GET("/blobs/*key", blobHandler.ServeSignedBlob)
+	}
 
 	ginEngine.Run(":" + os.Getenv("PORT"))
 }
@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"log"
+	"net/http"
 	"os"
 	"strings"
 	"time"
@@ -14,12 +15,52 @@ import (
 
 var identityKey = "id"
 
+var roleKey = "role"
+
 type User struct {
 	ID			uint
 	UUID 		string
 	UserName 	string  `json:"userName"`
 	Email       string
 	PhoneNumber string
+	Role        string
+}
+
+
+// Actor returns the identity services authorize actions against
+func (u *User) Actor() models.Actor {
+	return models.Actor{
+		ID:		u.ID,
+		Admin:	u.Role == models.AdminRole,
+	}
+}
+
+
+// RequirePermissions only lets requests through when the logged in user's
+// role grants every one of the given permissions
+func RequirePermissions(permissions ...models.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userDetails, _ := c.Get(identityKey)
+		user, ok := userDetails.(*User)
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"code":		http.StatusUnauthorized,
+				"message":	"User not authenticated",
+			})
+			return
+		}
+
+		for _, permission := range permissions {
+			if !models.HasPermission(user.Role, permission) {
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+					"code":		http.StatusForbidden,
+					"message":	"You do not have permission to perform this action",
+				})
+				return
+			}
+		}
+		c.Next()
+	}
 }
 
 
@@ -40,6 +81,7 @@ func Middleware(userService models.IUserService) (*jwt.GinJWTMiddleware, error)
 			if v, ok := data.(*User); ok {
 				return jwt.MapClaims{
 					identityKey: v.UUID,
+					roleKey: 	 v.Role,
 				}
 			}
 			return jwt.MapClaims{}
@@ -55,9 +97,12 @@ func Middleware(userService models.IUserService) (*jwt.GinJWTMiddleware, error)
 				return err
 			}
 
+			// The role is read from the database rather than the token so
+			// that promotions and demotions apply without a new login
 			return &User{
 				UUID:			user.UUID.String(),
 				ID:				user.ID,
+				Role:			user.Role,
 			}
 		},
 
@@ -85,6 +130,7 @@ func Middleware(userService models.IUserService) (*jwt.GinJWTMiddleware, error)
 					UUID:			user.UUID.String(),
 					UserName: 		user.UserName,
 					PhoneNumber: 	user.PhoneNumber,
+					Role:			user.Role,
 				}, nil
 			}
 
@@ -92,7 +138,8 @@ func Middleware(userService models.IUserService) (*jwt.GinJWTMiddleware, error)
 		},
 
 		Authorizator: func(data interface{}, c *gin.Context) bool {
-			return true
+			_, ok := data.(*User)
+			return ok
 		},
 
 		Unauthorized: func(c *gin.Context, code int, message string) {
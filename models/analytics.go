@@ -0,0 +1,72 @@
+package models
+
+import (
+	"time"
+)
+
+
+const (
+	// ViewRetention is how long raw item views are kept once aggregated into daily stats
+	ViewRetention = 7 * 24 * time.Hour
+
+	DefaultAnalyticsDays = 30
+	MaxAnalyticsDays     = 366
+)
+
+
+// ItemView is one user looking at an item on one day. Each user counts at
+// most once per item per day.
+type ItemView struct {
+	ID          uint       `gorm:"primarykey"`
+	ItemId      uint       `gorm:"not null;uniqueIndex:idx_item_views_viewer_day"`
+	ViewerKey   string     `gorm:"not null;uniqueIndex:idx_item_views_viewer_day"`
+	Day         time.Time  `gorm:"type:date;not null;uniqueIndex:idx_item_views_viewer_day;index"`
+	CreatedAt   time.Time
+}
+
+
+// ItemDailyStat counts what happened to an item on one day
+type ItemDailyStat struct {
+	ItemId        uint       `json:"-" gorm:"primaryKey;autoIncrement:false"`
+	Day           time.Time  `json:"day" gorm:"type:date;primaryKey"`
+	Views         int64      `json:"views"`
+	Watches       int64      `json:"watches"`
+	SwapRequests  int64      `json:"swapRequests"`
+}
+
+
+// ItemAnalytics summarises an item's performance over a range of days.
+// Conversion is the share of views that led to a swap request.
+type ItemAnalytics struct {
+	ItemId        uint             `json:"itemId"`
+	From          time.Time        `json:"from"`
+	To            time.Time        `json:"to"`
+	Views         int64            `json:"views"`
+	Watches       int64            `json:"watches"`
+	SwapRequests  int64            `json:"swapRequests"`
+	Conversion    float64          `json:"conversion"`
+	Watchers      int64            `json:"watchers"`  // Users watching the item right now
+	Daily         []ItemDailyStat  `json:"daily"`
+}
+
+
+type IAnalyticsRepository interface {
+	RecordView(itemId uint, viewerKey string, day time.Time) error
+	AggregateDailyStats(since time.Time) error
+	PruneViews(before time.Time) error
+	GetDailyStats(itemId uint, from, to time.Time) ([]ItemDailyStat, error)
+	CountWatchers(itemId uint) (int64, error)
+}
+
+
+type IAnalyticsService interface {
+	RecordView(itemId uint, viewerKey string)
+	GetItemAnalytics(actor Actor, itemId int, from, to time.Time) (*ItemAnalytics, error)
+	AggregateDailyStats() error
+}
+
+
+// Day returns the UTC calendar day t falls on
+func Day(t time.Time) time.Time {
+	return t.UTC().Truncate(24 * time.Hour)
+}
@@ -0,0 +1,202 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+
+const (
+	StringAttribute  = "string"
+	NumberAttribute  = "number"
+	BooleanAttribute = "boolean"
+	EnumAttribute    = "enum"
+)
+
+
+// AttributeField describes one typed field items in a category can carry,
+// e.g. storage in GB for phones or frame size for bicycles
+type AttributeField struct {
+	Name        string      `json:"name"`
+	Type        string      `json:"type"`
+	Required    bool        `json:"required"`
+	Enum        []string    `json:"enum,omitempty"`
+	Min         *float64    `json:"min,omitempty"`
+	Max         *float64    `json:"max,omitempty"`
+	Unit        string      `json:"unit,omitempty"`
+}
+
+
+// AttributeSchema is the list of attribute fields defined for a category
+type AttributeSchema []AttributeField
+
+
+// Attributes holds the attribute values of a single item
+type Attributes map[string]interface{}
+
+
+func (s AttributeSchema) Value() (driver.Value, error) {
+	if s == nil {
+		return "[]", nil
+	}
+	data, err := json.Marshal(s)
+	return string(data), err
+}
+
+
+func (s *AttributeSchema) Scan(value interface{}) error {
+	return scanJSON(value, s)
+}
+
+
+func (a Attributes) Value() (driver.Value, error) {
+	if a == nil {
+		return "{}", nil
+	}
+	data, err := json.Marshal(a)
+	return string(data), err
+}
+
+
+func (a *Attributes) Scan(value interface{}) error {
+	return scanJSON(value, a)
+}
+
+
+func scanJSON(value interface{}, dest interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []byte:
+		return json.Unmarshal(v, dest)
+	case string:
+		return json.Unmarshal([]byte(v), dest)
+	}
+	return fmt.Errorf("unsupported JSON value of type %T", value)
+}
+
+
+// Field returns the schema field with the given name
+func (s AttributeSchema) Field(name string) (AttributeField, bool) {
+	for _, field := range s {
+		if field.Name == name {
+			return field, true
+		}
+	}
+	return AttributeField{}, false
+}
+
+
+// Check verifies that the schema itself is well formed before it is saved
+func (s AttributeSchema) Check() error {
+	var problems []string
+	seen := map[string]bool{}
+
+	for i, field := range s {
+		name := field.Name
+		if strings.TrimSpace(name) == "" {
+			problems = append(problems, fmt.Sprintf("schema[%d]: name is required", i))
+			continue
+		}
+
+		if seen[name] {
+			problems = append(problems, fmt.Sprintf("%s: is defined more than once", name))
+		}
+		seen[name] = true
+
+		switch field.Type {
+		case StringAttribute, BooleanAttribute:
+		case EnumAttribute:
+			if len(field.Enum) == 0 {
+				problems = append(problems, fmt.Sprintf("%s: enum fields need at least one option", name))
+			}
+		case NumberAttribute:
+			if field.Min != nil && field.Max != nil && *field.Min > *field.Max {
+				problems = append(problems, fmt.Sprintf("%s: min cannot be greater than max", name))
+			}
+		default:
+			problems = append(problems, fmt.Sprintf("%s: unknown type %q", name, field.Type))
+		}
+	}
+
+	return joinProblems(problems)
+}
+
+
+// Validate checks item attribute values against the schema.
+// Errors use the "field: message; field: message" format understood by api.BindData.
+func (s AttributeSchema) Validate(attributes Attributes) error {
+	var problems []string
+
+	for _, field := range s {
+		value, ok := attributes[field.Name]
+		if !ok || value == nil {
+			if field.Required {
+				problems = append(problems, fmt.Sprintf("%s: is required", field.Name))
+			}
+			continue
+		}
+
+		if problem := field.check(value); problem != "" {
+			problems = append(problems, fmt.Sprintf("%s: %s", field.Name, problem))
+		}
+	}
+
+	for name := range attributes {
+		if _, ok := s.Field(name); !ok {
+			problems = append(problems, fmt.Sprintf("%s: is not an attribute of this category", name))
+		}
+	}
+
+	return joinProblems(problems)
+}
+
+
+func (f AttributeField) check(value interface{}) string {
+	switch f.Type {
+	case StringAttribute:
+		if _, ok := value.(string); !ok {
+			return "must be text"
+		}
+	case BooleanAttribute:
+		if _, ok := value.(bool); !ok {
+			return "must be true or false"
+		}
+	case EnumAttribute:
+		text, ok := value.(string)
+		if !ok {
+			return "must be one of " + strings.Join(f.Enum, ", ")
+		}
+		for _, option := range f.Enum {
+			if option == text {
+				return ""
+			}
+		}
+		return "must be one of " + strings.Join(f.Enum, ", ")
+	case NumberAttribute:
+		number, ok := value.(float64)
+		if !ok {
+			return "must be a number"
+		}
+		if f.Min != nil && number < *f.Min {
+			return fmt.Sprintf("must be at least %v", *f.Min)
+		}
+		if f.Max != nil && number > *f.Max {
+			return fmt.Sprintf("must be at most %v", *f.Max)
+		}
+	}
+	return ""
+}
+
+
+func joinProblems(problems []string) error {
+	if len(problems) == 0 {
+		return nil
+	}
+	sort.Strings(problems)
+	return errors.New(strings.Join(problems, "; "))
+}
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+
+// ErrBlobNotFound is returned by blob stores for keys that hold nothing
+var ErrBlobNotFound = errors.New("blob not found")
+
+
+// BlobInfo describes a stored blob
+type BlobInfo struct {
+	Key          string     `json:"key"`
+	Size         int64      `json:"size"`
+	ContentType  string     `json:"contentType"`
+	ModTime      time.Time  `json:"modTime"`
+}
+
+
+// IBlobStore keeps uploaded files. Keys are slash separated paths such as
+// "item_12/1700000000_bike.jpg".
+type IBlobStore interface {
+	// Put stores body under key; size may be -1 when it is not known up front
+	Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, *BlobInfo, error)
+	Delete(ctx context.Context, key string) error
+	Stat(ctx context.Context, key string) (*BlobInfo, error)
+	List(ctx context.Context, prefix string) ([]BlobInfo, error)
+	// SignedURL returns a URL anyone can fetch the blob from until it expires
+	SignedURL(ctx context.Context, key string, expiry time.Duration) (string, error)
+}
+
+
+// IBlobURLVerifier is implemented by stores whose signed URLs are served by
+// this application rather than by the store itself
+type IBlobURLVerifier interface {
+	VerifySignedURL(key string, expires int64, signature string) bool
+}
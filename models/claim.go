@@ -0,0 +1,66 @@
+package models
+
+import (
+	"time"
+)
+
+
+// How the recipient of a give-away item is picked
+const (
+	OwnerPicksClaims = "OWNER_PICKS"
+	FirstComeClaims  = "FIRST_COME"
+)
+
+
+// Claim statuses
+const (
+	ClaimWaiting   = "WAITING"
+	ClaimChosen    = "CHOSEN"
+	ClaimCompleted = "COMPLETED"
+	ClaimDeclined  = "DECLINED"
+)
+
+
+// Claim is a user's place in the queue for a give-away item. Withdrawing
+// deletes the claim, so it has no soft delete.
+type Claim struct {
+	ID          uint       `gorm:"primarykey" json:"id"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	UpdatedAt   time.Time  `json:"updatedAt"`
+	ItemId      uint       `json:"itemId" gorm:"not null;uniqueIndex:idx_claims_item_user"`
+	UserId      uint       `json:"userId" gorm:"not null;uniqueIndex:idx_claims_item_user;index"`
+	Status      string     `json:"status" gorm:"not null;default:WAITING"`
+	Message     string     `json:"message"` // Shown to the owner when picking a recipient
+}
+
+
+// IsValidClaimMode reports whether mode is a known way of picking a recipient
+func IsValidClaimMode(mode string) bool {
+	return mode == OwnerPicksClaims || mode == FirstComeClaims
+}
+
+
+// ClaimOutcome is what completing a give-away changed
+type ClaimOutcome struct {
+	Completed       Claim
+	DeclinedUserIds []uint
+}
+
+
+type IClaimRepository interface {
+	CreateClaim(itemId, userId uint, message string) (*Claim, error)
+	// WithdrawClaim removes the user's claim, returning the claim that replaced it as chosen, if any
+	WithdrawClaim(itemId, userId uint) (*Claim, error)
+	GetClaims(itemId uint, page PageRequest) ([]Claim, *Page, error)
+	ChooseClaim(itemId, claimId uint) (*Claim, error)
+	CompleteClaim(itemId uint) (*ClaimOutcome, error)
+}
+
+
+type IClaimService interface {
+	CreateClaim(userId, itemId uint, message string) (*Claim, error)
+	WithdrawClaim(userId, itemId uint) error
+	GetClaims(actor Actor, itemId uint, page PageRequest) ([]Claim, *Page, error)
+	ChooseClaim(actor Actor, itemId, claimId uint) (*Claim, error)
+	CompleteClaim(actor Actor, itemId uint) (*Claim, error)
+}
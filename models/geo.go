@@ -0,0 +1,39 @@
+package models
+
+import (
+	"math"
+)
+
+
+const EarthRadiusKm = 6371.0
+
+
+// Coordinates is a point on the earth in decimal degrees
+type Coordinates struct {
+	Latitude    float64  `json:"latitude"`
+	Longitude   float64  `json:"longitude"`
+}
+
+
+// IGeocoder turns a free-text address such as a user's location into coordinates
+type IGeocoder interface {
+	Geocode(address string) (*Coordinates, error)
+}
+
+
+// Valid reports whether the coordinates lie within the range of latitudes and longitudes
+func (c Coordinates) Valid() bool {
+	return c.Latitude >= -90 && c.Latitude <= 90 && c.Longitude >= -180 && c.Longitude <= 180
+}
+
+
+// DistanceKm is the great-circle distance between two points, using the haversine formula
+func (c Coordinates) DistanceKm(other Coordinates) float64 {
+	lat1 := c.Latitude * math.Pi / 180
+	lat2 := other.Latitude * math.Pi / 180
+	dLat := lat2 - lat1
+	dLng := (other.Longitude - c.Longitude) * math.Pi / 180
+
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * EarthRadiusKm * math.Asin(math.Min(1, math.Sqrt(a)))
+}
@@ -1,5 +1,12 @@
 package models
 
+import (
+	"io"
+	"path"
+	"strings"
+	"time"
+)
+
 
 type Image struct {
 	Base
@@ -8,19 +15,142 @@ type Image struct {
 	ItemId   uint   `json:"itemId" gorm:"not null"` // Foreign key to Item
 	Item     Item   `gorm:"foreignKey:ItemId" json:"-"` // Item relationship
 	OwnerId  uint   `json:"ownerId"`
+	Position int    `json:"position" gorm:"not null;default:0"` // Order among the item's images; the first is the cover
+	Hash     *int64 `json:"-"` // Difference hash, used to spot duplicate listings
+	ContentType string `json:"contentType"` // Sniffed from the file's contents at upload
+	Size     int64  `json:"size"`
+	Checksum string `json:"-"` // Hex SHA-256 of the file, used as its ETag
+	VariantsAt *time.Time `json:"-"` // When the resized variants were stored
+}
+
+
+// Upload limits. Uploads are re-encoded, so only formats that can be decoded
+// are accepted.
+const (
+	MaxImageUploadSize = 5 << 20
+	MaxImagesPerItem   = 10
+)
+
+
+var allowedImageTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
 }
 
+
+// IsAllowedImageType reports whether files sniffed as contentType may be uploaded
+func IsAllowedImageType(contentType string) bool {
+	return allowedImageTypes[contentType]
+}
+
+
+// Image sizes served alongside the original upload
+const (
+	ThumbnailSize = "thumbnail"
+	MediumSize    = "medium"
+	LargeSize     = "large"
+)
+
+
+// ImageSizes maps each variant to the longest side it is scaled down to
+var ImageSizes = map[string]int{
+	ThumbnailSize: 150,
+	MediumSize:    600,
+	LargeSize:     1200,
+}
+
+
+func IsValidImageSize(size string) bool {
+	_, ok := ImageSizes[size]
+	return ok
+}
+
+
+// Key is where the image is kept in the blob store
+func (i Image) Key() string {
+	return path.Join(i.FilePath, i.FileName)
+}
+
+
+// VariantKey is where the resized copy of the image for size is kept, next to
+// the original
+func (i Image) VariantKey(size string) string {
+	name := strings.TrimSuffix(i.FileName, path.Ext(i.FileName))
+	extension := ".jpg"
+	if i.VariantContentType() == "image/png" {
+		extension = ".png"
+	}
+	return path.Join(i.FilePath, size, name+extension)
+}
+
+
+// VariantContentType is the format resized copies are encoded in. PNG and GIF
+// sources stay lossless so transparency survives; everything else becomes JPEG.
+func (i Image) VariantContentType() string {
+	if i.ContentType == "image/png" || i.ContentType == "image/gif" {
+		return "image/png"
+	}
+	return "image/jpeg"
+}
+
+
+// ImageContent is an open image ready to be served. Close must be called once it has been.
+type ImageContent struct {
+	Image    Image
+	Content  io.ReadSeeker
+	ModTime  time.Time
+	Closer   io.Closer
+}
+
+
+func (c *ImageContent) Close() error {
+	if c.Closer == nil {
+		return nil
+	}
+	return c.Closer.Close()
+}
+
+
 type IImageRepository interface {
-	UploadImage(itemId int, folderName, fileName string) error
-	ReadFirstImageById(id int) ([]byte, error)
-	ReadAllImagesByItemId(id, limit, page int) ([]Image, error)
+	UploadImage(image *Image) error
+	GetImage(imageId int) (*Image, error)
+	GetFirstImage(itemId int) (*Image, error)
+	ReadAllImagesByItemId(id int, page PageRequest) ([]Image, *Page, error)
+	CountImages(itemId uint) (int64, error)
+	// GetImagesByItemId lists every image of an item in display order
+	GetImagesByItemId(itemId uint) ([]Image, error)
+	// DeleteImage removes an image's record for good, since its files go with it
+	DeleteImage(imageId uint) error
+	// CountImagesByFile counts the records pointing at a stored file
+	CountImagesByFile(filePath, fileName string) (int64, error)
+	// ReorderImages sets the order of an item's images; imageIds must list each of them once
+	ReorderImages(itemId uint, imageIds []uint) error
+	// MarkVariantsReady fails with a not-found error once the image has been deleted
+	MarkVariantsReady(imageId uint) error
+	GetImagesWithoutVariants(afterId uint, limit int) ([]Image, error)
 }
 
 
 type IImageService interface {
-	// UploadImage(itemId int, folderName, fileName string) error
-	ReadImage(folderName, fileName string) ([]byte, error)
-	ReadFirstImageById(id int) ([]byte, error)
-	ReadAllImagesByItemId(id, limit, page int) ([]Image, error)
-	ReadImageByPath(path string) ([]byte, error)
-}
\ No newline at end of file
+	// OpenImage opens an image by its own ID, resized to size when it is not empty
+	OpenImage(imageId int, size string) (*ImageContent, error)
+	// OpenFirstImage opens the first image of an item, resized to size when it is not empty
+	OpenFirstImage(itemId int, size string) (*ImageContent, error)
+	ReadAllImagesByItemId(id int, page PageRequest) ([]Image, *Page, error)
+	// DeleteImage removes an image of an item along with its files
+	DeleteImage(actor Actor, itemId, imageId uint) error
+	// ReorderImages puts an item's images in the given order, the first becoming its cover
+	ReorderImages(actor Actor, itemId uint, imageIds []uint) ([]Image, error)
+	// SetCoverImage moves an image to the front of its item's images
+	SetCoverImage(actor Actor, itemId, imageId uint) ([]Image, error)
+}
+
+
+// IThumbnailService resizes uploaded images into every size in ImageSizes
+type IThumbnailService interface {
+	// Enqueue queues an image to be resized in the background
+	Enqueue(image Image)
+	// GenerateMissing resizes every image whose variants have not been stored yet
+	GenerateMissing() error
+}
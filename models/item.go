@@ -1,6 +1,7 @@
 package models
 
 import (
+	"mime/multipart"
 	"time"
 )
 
@@ -16,58 +17,142 @@ type Item struct {
 	User          User      `gorm:"foreignKey:OwnerId" json:"-"` // Owner relationship
 	OwnerId       uint      `json:"-"`
 	SoldAt        time.Time `json:"soldAt"`
+	Attributes    Attributes `json:"attributes" gorm:"type:jsonb;default:'{}'"` // Values for the category's attribute schema
+	Latitude      *float64  `json:"latitude" gorm:"index:idx_items_location"`  // Defaults to the owner's location
+	Longitude     *float64  `json:"longitude" gorm:"index:idx_items_location"`
+	Revision      int       `json:"revision" gorm:"not null;default:1"` // Current ItemRevision
+	Giveaway      bool      `json:"giveaway" gorm:"not null;default:false"` // Given away through a claim queue instead of sold
+	ClaimMode     string    `json:"claimMode,omitempty"` // How the recipient of a give-away is picked
+	Questions     []Question `json:"questions,omitempty" gorm:"-"` // First page of answered questions, filled in when showing the item
+	QuestionsPage *Page      `json:"questionsPage,omitempty" gorm:"-"` // Where Questions sits; the rest come from the item's questions endpoint
+	ListingDays   int        `json:"listingDays,omitempty"` // How long each listing runs, zero for no limit
+	ExpiresAt     *time.Time `json:"expiresAt" gorm:"index"`
+	Expired       bool       `json:"expired" gorm:"not null;default:false"`
+	RemindedAt    *time.Time `json:"-"` // When the owner was last reminded of the expiry
 	Images        []Image   `json:"images" gorm:"constraint:OnDelete:CASCADE"` // Images with cascade delete
 }
 
 
 type Category struct {
 	Base
-	Name 	string `json:"name" gorm:"unique"`
-	Ban     bool   `json:"ban" gorm:"type:boolean; defaut:false"`
+	Name 	 string     `json:"name" gorm:"unique"`
+	Slug     string     `json:"slug" gorm:"index:idx_categories_slug,unique,where:slug <> ''"`
+	Ban      bool       `json:"ban" gorm:"type:boolean; defaut:false"`
+	ParentId *uint      `json:"parentId" gorm:"index"`
+	Children []Category `json:"children,omitempty" gorm:"foreignKey:ParentId"`
+	AttributeSchema AttributeSchema `json:"attributeSchema" gorm:"type:jsonb;default:'[]'"`
 }
+// Coordinates returns where the item is, or nil when it has no location
+func (i Item) Coordinates() *Coordinates {
+	if i.Latitude == nil || i.Longitude == nil {
+		return nil
+	}
+	return &Coordinates{Latitude: *i.Latitude, Longitude: *i.Longitude}
+}
+
+
+// ItemSearchHit is an item matched by a free-text search, with its relevance
+// and the matching parts of its name and description wrapped in <mark> tags.
+// Headline and Snippet are safe HTML: everything but the marks is escaped.
+type ItemSearchHit struct {
+	Item
+	Rank        float64  `json:"rank"`
+	Headline    string   `json:"headline"`
+	Snippet     string   `json:"snippet"`
+}
+
+
+// CategoryChangeReport summarises what a category maintenance operation
+// changed, or would change when run as a dry run
+type CategoryChangeReport struct {
+	DryRun          bool    `json:"dryRun"`
+	Items           int64   `json:"items"`
+	Subcategories   int64   `json:"subcategories"`
+	// Problems lists items whose attributes do not fit the target category's
+	// schema; the change is only made when there are none
+	Problems        []CategoryItemProblem  `json:"problems,omitempty"`
+}
+
+
+// CategoryItemProblem is an item that cannot be moved into a category, and why
+type CategoryItemProblem struct {
+	ItemId   uint    `json:"itemId"`
+	Problem  string  `json:"problem"`
+}
+
+
 type ICategoryRepository interface {
-	CreateCategory(name string) (*Category, error)
+	CreateCategory(name string, parentSlug string) (*Category, error)
 	DeleteCategory(name string) error
 	GetAllValidCategories() ([]Category, error)
-	GetAllItemsInCategory(id, limit, page int) ([]Item, error)
+	GetAllItemsInCategory(id int, page PageRequest) ([]Item, *Page, error)
 	BanCategory(name string) error
 	CheckStatus(name string) (bool, error)
 	UnBanCategory(name string) error
+	GetCategory(nameOrSlug string) (*Category, error)
+	UpdateAttributeSchema(id int, schema AttributeSchema) error
+	RenameCategory(name, newName string, dryRun bool) (*CategoryChangeReport, error)
+	MergeCategory(source, target string, dryRun bool) (*CategoryChangeReport, error)
+	ReassignItems(itemIds []uint, target string, dryRun bool) (*CategoryChangeReport, error)
 }
 type ICategoryService interface {
-	CreateCategory(name string) (*Category, error)
+	CreateCategory(name string, parentSlug string) (*Category, error)
 	DeleteCategory(name string) error
 	GetAllValidCategories() ([]Category, error)
-	GetAllItemsInCategory(id, limit, page int) ([]Item, error)
+	GetAllItemsInCategory(id int, page PageRequest) ([]Item, *Page, error)
 	BanCategory(name string) error
 	CheckStatus(name string) (bool, error)
 	UnBanCategory(name string) error
+	UpdateAttributeSchema(id int, schema AttributeSchema) error
+	RenameCategory(name, newName string, dryRun bool) (*CategoryChangeReport, error)
+	MergeCategory(source, target string, dryRun bool) (*CategoryChangeReport, error)
+	ReassignItems(itemIds []uint, target string, dryRun bool) (*CategoryChangeReport, error)
+}
+
+
+// IItemObserver is told about new listings and changes to existing ones, e.g.
+// to alert users interested in them. Observers are called in the background
+// and handle their own errors.
+type IItemObserver interface {
+	ItemRegistered(item Item)
+	ItemUpdated(before, after Item)
 }
 
 
 type IItemRepository interface {
 	GetItemById(id int) (*Item, error)
 	GetByUUID(uuid string) (*Item, error)
-	GetItemsByCategory(category string, limit, page int) ([]Item, error)
+	QueryItems(query ItemQuery, page PageRequest) ([]Item, *ItemFacets, *Page, error)
 	RegisterItem(item *Item) (*Item, error)
-	GetUnsoldItemsByCategory(category string, limit, page int) ([]Item, error)
+	RegisterItems(items []*Item) error
 	UpdateItem(item Item) error
 	DeleteItem(itemId int) error
-	GetItemsByOwnerId(ownerId uint, limit, page int) ([]Item, error)
+	GetItemsByOwnerId(ownerId uint, page PageRequest) ([]Item, *Page, error)
 	BuyItem(userId, itemId int, amount float64) (string, error)
 	UpdateCategory(itemId int, categoryName string) error
+	SearchItems(query string, page PageRequest) ([]ItemSearchHit, *Page, error)
+	GetItemRevisions(itemId uint) ([]ItemRevision, error)
+	RelistItem(itemId uint, prize *float64, expiresAt *time.Time) error
+	GetItemsExpiringBefore(before time.Time) ([]Item, error)
+	MarkReminded(itemIds []uint) error
+	ExpireItems(now time.Time) ([]Item, error)
 }
 
 
 type IItemService interface {
 	RegisterItem(item *Item) (*Item, error)
+	ImportItems(ownerId uint, rows []ItemImportRow, dryRun bool) (*ImportReport, error)
 	GetItemById(id int) (*Item, error)
 	GetItemByUUID(uuid string) (*Item, error)
-	GetItemsByCategory(category string, limit, page int) ([]Item, error)
-	GetUnsoldItemsByCategory(category string, limit, page int) ([]Item, error)
-	UpdateItem(item Item) error
-	DeleteItem(itemId int) error
+	QueryItems(query ItemQuery, page PageRequest) ([]Item, *ItemFacets, *Page, error)
+	UpdateItem(actor Actor, item Item) error
+	DeleteItem(actor Actor, itemId int) error
 	BuyItem(userId, itemId int, amount float64) (string, error)
-	GetItemsByOwnerId(ownerId uint, limit, page int) ([]Item, error)
-	UpdateCategory(itemId int, categoryName string) error
+	GetItemsByOwnerId(ownerId uint, page PageRequest) ([]Item, *Page, error)
+	UpdateCategory(actor Actor, itemId int, categoryName string) error
+	UploadImage(actor Actor, itemId int, file *multipart.FileHeader) (string, error)
+	SearchItems(query string, page PageRequest) ([]ItemSearchHit, *Page, error)
+	GetItemRevisions(itemId int) ([]ItemRevisionDiff, error)
+	// RelistItem puts an item back on the market for another listing period, optionally cheaper
+	RelistItem(actor Actor, itemId int, prize *float64, days int) error
 }
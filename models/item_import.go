@@ -0,0 +1,45 @@
+package models
+
+
+const (
+	// ImportBatchSize is how many items are inserted per database round trip
+	ImportBatchSize = 100
+
+	// MaxImportRows caps the size of one import
+	MaxImportRows = 5000
+)
+
+
+// Import file formats
+const (
+	CSVImport   = "csv"
+	JSONLImport = "jsonl"
+)
+
+
+// ItemImportRow is one parsed line of an import file. Error is set when the
+// line could not be turned into a valid item.
+type ItemImportRow struct {
+	Line    int
+	Item    *Item
+	Error   string
+}
+
+
+// ImportRowResult reports what happened to one line of an import file
+type ImportRowResult struct {
+	Line    int     `json:"line"`
+	ItemId  uint    `json:"itemId,omitempty"`
+	Error   string  `json:"error,omitempty"`
+}
+
+
+// ImportReport summarises a bulk import. In a dry run nothing is saved and
+// Imported counts the rows that would have been.
+type ImportReport struct {
+	DryRun    bool               `json:"dryRun"`
+	Total     int                `json:"total"`
+	Imported  int                `json:"imported"`
+	Failed    int                `json:"failed"`
+	Rows      []ImportRowResult  `json:"rows"`
+}
@@ -0,0 +1,68 @@
+package models
+
+import (
+	"time"
+)
+
+
+// Sort keys understood by ItemQuery. Without one items come back in listing order.
+const (
+	SortNewest    = "newest"
+	SortOldest    = "oldest"
+	SortPriceAsc  = "price_asc"
+	SortPriceDesc = "price_desc"
+	SortDistance  = "distance"
+)
+
+
+// SortKeys lists every valid ItemQuery sort key
+var SortKeys = []string{SortNewest, SortOldest, SortPriceAsc, SortPriceDesc, SortDistance}
+
+
+// PriceBucketBounds are the edges of the price facet buckets.
+// Bucket i holds prices from bound i-1 (inclusive) up to bound i (exclusive);
+// the first bucket starts at zero and the last one is open ended.
+var PriceBucketBounds = []float64{10, 50, 100, 500, 1000}
+
+
+// ItemQuery combines every filter a buyer can apply when browsing items.
+// Zero values mean "don't filter on this".
+type ItemQuery struct {
+	Category      string
+	MinPrize      *float64
+	MaxPrize      *float64
+	ListedAfter   *time.Time
+	ListedBefore  *time.Time
+	Sold          *bool
+	Giveaway      *bool
+	Location      string
+	Attributes    Attributes
+	Near          *Coordinates
+	NearAddress   string   // Geocoded into Near when no coordinates are given
+	RadiusKm      float64  // Only used together with Near; zero means no limit
+	Sort          string
+}
+
+
+// CategoryFacet is the number of matching items in one category
+type CategoryFacet struct {
+	Name    string  `json:"name"`
+	Count   int64   `json:"count"`
+}
+
+
+// PriceFacet is the number of matching items priced within [Min, Max).
+// Max is nil for the open ended top bucket.
+type PriceFacet struct {
+	Min     float64   `json:"min"`
+	Max     *float64  `json:"max"`
+	Count   int64     `json:"count"`
+}
+
+
+// ItemFacets summarises how the items matching a query are spread out.
+// Each facet ignores its own filter, so it shows what choosing another value would return.
+type ItemFacets struct {
+	Categories  []CategoryFacet  `json:"categories"`
+	Prices      []PriceFacet     `json:"prices"`
+}
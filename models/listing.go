@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+)
+
+
+// MaxListingDays caps how long a listing can run before it has to be relisted
+const MaxListingDays = 90
+
+
+// ExpiryReminderWindow is how long before a listing expires its owner is reminded
+const ExpiryReminderWindow = 3 * 24 * time.Hour
+
+
+// ListingExpiry returns when a listing of the given duration starting at from
+// runs out, or nil for listings without a duration
+func ListingExpiry(from time.Time, days int) *time.Time {
+	if days <= 0 {
+		return nil
+	}
+	expiresAt := from.AddDate(0, 0, days).Truncate(time.Second)
+	return &expiresAt
+}
+
+
+type IListingService interface {
+	// SendExpiryReminders emails owners whose listings expire within ExpiryReminderWindow
+	SendExpiryReminders() error
+	// ExpireListings takes listings past their expiry off the market
+	ExpireListings() error
+}
@@ -0,0 +1,89 @@
+package models
+
+import (
+	"image"
+	"time"
+)
+
+
+// Duplicate detection thresholds. Text similarity is the Jaccard index of the
+// shingles of a listing's name and description; image distance is the number
+// of differing bits between two 64-bit difference hashes.
+const (
+	DuplicateShingleSize        = 5
+	DuplicateBlockSimilarity    = 0.9
+	DuplicateFlagSimilarity     = 0.7
+	DuplicateImageBlockDistance = 2
+	DuplicateImageFlagDistance  = 6
+	DuplicateLookback           = 7 * 24 * time.Hour // How far back other users' listings are compared
+	DuplicateCandidateLimit     = 200
+)
+
+
+// Why a listing was flagged
+const (
+	DuplicateTextFlag  = "DUPLICATE_TEXT"
+	DuplicateImageFlag = "DUPLICATE_IMAGE"
+)
+
+
+// Listing flag statuses. Removing a flagged listing deletes it.
+const (
+	FlagOpen      = "OPEN"
+	FlagDismissed = "DISMISSED"
+	FlagRemoved   = "REMOVED"
+)
+
+
+// ListingFlag marks a listing as a likely duplicate of another for moderators to review
+type ListingFlag struct {
+	ID             uint        `gorm:"primarykey" json:"id"`
+	CreatedAt      time.Time   `json:"createdAt"`
+	UpdatedAt      time.Time   `json:"updatedAt"`
+	ItemId         uint        `json:"itemId" gorm:"not null;index"`
+	Item           Item        `json:"item" gorm:"foreignKey:ItemId"`
+	MatchedItemId  uint        `json:"matchedItemId"`
+	Reason         string      `json:"reason"`
+	Similarity     float64     `json:"similarity"`
+	Status         string      `json:"status" gorm:"not null;default:OPEN;index"`
+	ReviewedBy     *uint       `json:"reviewedBy"`
+	ReviewedAt     *time.Time  `json:"reviewedAt"`
+}
+
+
+// ImageHash is the difference hash of one of an item's images
+type ImageHash struct {
+	ItemId   uint
+	OwnerId  uint
+	Hash     int64
+}
+
+
+type IListingFlagRepository interface {
+	// GetCandidateItems returns the owner's unsold listings and other users'
+	// recent listings with a similar name
+	GetCandidateItems(ownerId uint, name string, since time.Time) ([]Item, error)
+	// GetCandidateImageHashes returns the image hashes of the owner's other
+	// listings and of other users' recently uploaded images
+	GetCandidateImageHashes(ownerId, itemId uint, since time.Time) ([]ImageHash, error)
+	CreateFlags(flags []ListingFlag) error
+	GetFlags(status string, page PageRequest) ([]ListingFlag, *Page, error)
+	ReviewFlag(flagId, reviewerId uint, status string) (*ListingFlag, error)
+}
+
+
+// IDuplicateDetector screens new listings and images before they are saved.
+// Near-identical copies of the owner's own listings are rejected, other close
+// matches come back as flags to record once the listing exists.
+type IDuplicateDetector interface {
+	ScreenItem(item *Item) ([]ListingFlag, error)
+	// ScreenImage also returns the image's hash
+	ScreenImage(item *Item, img image.Image) (*int64, []ListingFlag, error)
+	RecordFlags(itemId uint, flags []ListingFlag)
+}
+
+
+type IModerationService interface {
+	GetFlags(status string, page PageRequest) ([]ListingFlag, *Page, error)
+	ReviewFlag(reviewerId, flagId uint, status string) (*ListingFlag, error)
+}
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+)
+
+
+// Notification kinds
+const (
+	SavedSearchNotification = "SAVED_SEARCH"
+	WatchlistNotification   = "WATCHLIST"
+	ClaimNotification       = "CLAIM"
+	QuestionNotification    = "QUESTION"
+	ListingNotification     = "LISTING"
+)
+
+
+// Notification is an in-app message for a user, optionally about an item
+type Notification struct {
+	Base
+	UserId  uint        `json:"-" gorm:"not null;index"`
+	Kind    string      `json:"kind"`
+	Title   string      `json:"title"`
+	Body    string      `json:"body"`
+	ItemId  *uint       `json:"itemId"`
+	ReadAt  *time.Time  `json:"readAt"`
+}
+
+
+type INotificationRepository interface {
+	CreateNotification(notification *Notification) error
+	GetNotifications(userId uint, unreadOnly bool, page PageRequest) ([]Notification, *Page, error)
+	MarkRead(userId uint, notificationIds []uint) error
+}
+
+
+type INotificationService interface {
+	// Notify stores an in-app notification and, when email is set, also emails it to the user
+	Notify(notification *Notification, email bool) error
+	GetNotifications(userId uint, unreadOnly bool, page PageRequest) ([]Notification, *Page, error)
+	MarkRead(userId uint, notificationIds []uint) error
+}
@@ -0,0 +1,96 @@
+package models
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+
+// PageRequest asks for one page of a list ordered by ID. Cursor is the opaque
+// value returned in a previous Page; without one, Page selects a page by number.
+type PageRequest struct {
+	Limit   int
+	Cursor  string
+	Page    int
+}
+
+
+// Page describes where a returned list sits within the full result
+type Page struct {
+	Limit       int     `json:"limit"`
+	Total       int64   `json:"total"`
+	NextCursor  string  `json:"nextCursor,omitempty"`
+	PrevCursor  string  `json:"prevCursor,omitempty"`
+}
+
+
+// Cursor marks a position in a list: rows after or before the given ID
+type Cursor struct {
+	After   uint
+	Before  uint
+}
+
+
+// NewPageRequest applies the default and maximum page sizes to a request
+func NewPageRequest(limit, page int, cursor string) PageRequest {
+	if limit <= 0 {
+		limit = DefaultPageSize
+	}
+	if limit > MaxPageSize {
+		limit = MaxPageSize
+	}
+	if page < 1 {
+		page = 1
+	}
+
+	return PageRequest{
+		Limit:  limit,
+		Cursor: cursor,
+		Page:   page,
+	}
+}
+
+
+func (c Cursor) Encode() string {
+	raw := fmt.Sprintf("a:%d", c.After)
+	if c.Before > 0 {
+		raw = fmt.Sprintf("b:%d", c.Before)
+	}
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+
+func DecodeCursor(value string) (Cursor, error) {
+	var cursor Cursor
+	if value == "" {
+		return cursor, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return cursor, errors.New("invalid cursor")
+	}
+
+	var direction string
+	var id uint
+	if _, err := fmt.Sscanf(string(raw), "%1s:%d", &direction, &id); err != nil || id == 0 {
+		return cursor, errors.New("invalid cursor")
+	}
+
+	switch direction {
+	case "a":
+		cursor.After = id
+	case "b":
+		cursor.Before = id
+	default:
+		return cursor, errors.New("invalid cursor")
+	}
+	return cursor, nil
+}
@@ -0,0 +1,8 @@
+package models
+
+
+// Actor is the authenticated user a service call is performed on behalf of
+type Actor struct {
+	ID      uint
+	Admin   bool
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+
+// Question is asked publicly about an item and answered by its owner.
+// Only answered questions are shown to everyone else.
+type Question struct {
+	ID          uint            `gorm:"primarykey" json:"id"`
+	CreatedAt   time.Time       `json:"createdAt"`
+	UpdatedAt   time.Time       `json:"updatedAt"`
+	DeletedAt   gorm.DeletedAt  `gorm:"index" json:"-"`
+	ItemId      uint            `json:"itemId" gorm:"not null;index"`
+	AskerId     uint            `json:"askerId" gorm:"not null"`
+	Body        string          `json:"body" gorm:"not null"`
+	Answer      string          `json:"answer,omitempty"`
+	AnsweredAt  *time.Time      `json:"answeredAt"`
+}
+
+
+type IQuestionRepository interface {
+	CreateQuestion(question *Question) error
+	GetQuestion(itemId, questionId uint) (*Question, error)
+	GetQuestions(itemId uint, answeredOnly bool, page PageRequest) ([]Question, *Page, error)
+	AnswerQuestion(question *Question, answer string) error
+	DeleteQuestion(question *Question) error
+}
+
+
+type IQuestionService interface {
+	AskQuestion(userId, itemId uint, body string) (*Question, error)
+	// GetQuestions returns every question to the item's owner and admins, and only answered ones to everyone else
+	GetQuestions(actor Actor, itemId uint, page PageRequest) ([]Question, *Page, error)
+	AnswerQuestion(actor Actor, itemId, questionId uint, answer string) (*Question, error)
+	DeleteQuestion(actor Actor, itemId, questionId uint) error
+}
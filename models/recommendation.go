@@ -0,0 +1,54 @@
+package models
+
+import (
+	"time"
+)
+
+
+const (
+	DefaultRecommendationLimit = 20
+	MaxRecommendationLimit     = 50
+
+	// SimilarItemsKept caps how many neighbours are stored per item
+	SimilarItemsKept = 50
+)
+
+
+// How strongly each kind of interaction ties a user to an item. When a user
+// interacted with an item in several ways, the strongest one counts.
+const (
+	ViewWeight        = 1.0
+	WatchWeight       = 3.0
+	SwapRequestWeight = 4.0
+	PurchaseWeight    = 5.0
+)
+
+
+// ItemSimilarity is how alike two items are judged to be by the users who
+// interacted with both. It is computed offline by a scheduled job.
+type ItemSimilarity struct {
+	ItemId         uint       `gorm:"primaryKey;autoIncrement:false"`
+	SimilarItemId  uint       `gorm:"primaryKey;autoIncrement:false"`
+	Score          float64    `gorm:"not null"`
+	ComputedAt     time.Time
+}
+
+
+// Recommendation is an unsold item suggested to a user with its relevance score
+type Recommendation struct {
+	Item
+	Score   float64  `json:"score"`
+}
+
+
+type IRecommendationRepository interface {
+	ComputeSimilarities() error
+	GetRecommendations(userId uint, limit int) ([]Recommendation, error)
+	GetPopularItems(userId uint, limit int) ([]Recommendation, error)
+}
+
+
+type IRecommendationService interface {
+	GetRecommendations(userId uint, limit int) ([]Recommendation, error)
+	ComputeSimilarities() error
+}
@@ -0,0 +1,102 @@
+package models
+
+import (
+	"reflect"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+
+// ItemRevision is a snapshot of the buyer-facing details of an item. A new
+// revision is stored whenever those details change, so swaps and purchases
+// can point at exactly what the other party agreed to.
+type ItemRevision struct {
+	ID            uint        `gorm:"primarykey" json:"-"`
+	CreatedAt     time.Time   `json:"createdAt"`
+	ItemId        uint        `json:"itemId" gorm:"not null;uniqueIndex:idx_item_revisions_item_revision"`
+	Revision      int         `json:"revision" gorm:"not null;uniqueIndex:idx_item_revisions_item_revision"`
+	Name          string      `json:"name"`
+	Description   string      `json:"description"`
+	CategoryName  string      `json:"categoryName"`
+	Prize         float64     `json:"prize" gorm:"type:numeric(19,2)"`
+	Attributes    Attributes  `json:"attributes" gorm:"type:jsonb;default:'{}'"`
+	Latitude      *float64    `json:"latitude"`
+	Longitude     *float64    `json:"longitude"`
+}
+
+
+// FieldChange is one field that differs between two revisions
+type FieldChange struct {
+	Field   string       `json:"field"`
+	From    interface{}  `json:"from"`
+	To      interface{}  `json:"to"`
+}
+
+
+// ItemRevisionDiff is a revision together with what changed since the one before it
+type ItemRevisionDiff struct {
+	ItemRevision
+	Changes  []FieldChange  `json:"changes"`
+}
+
+
+// RevisionOf snapshots the current details of an item
+func RevisionOf(item *Item) ItemRevision {
+	return ItemRevision{
+		ItemId:       item.ID,
+		Name:         item.Name,
+		Description:  item.Description,
+		CategoryName: item.CategoryName,
+		Prize:        item.Prize,
+		Attributes:   item.Attributes,
+		Latitude:     item.Latitude,
+		Longitude:    item.Longitude,
+	}
+}
+
+
+// Diff lists the fields that changed from previous to r
+func (r ItemRevision) Diff(previous ItemRevision) []FieldChange {
+	changes := []FieldChange{}
+
+	add := func(field string, from, to interface{}) {
+		if !reflect.DeepEqual(from, to) {
+			changes = append(changes, FieldChange{Field: field, From: from, To: to})
+		}
+	}
+
+	add("name", previous.Name, r.Name)
+	add("description", previous.Description, r.Description)
+	add("categoryName", previous.CategoryName, r.CategoryName)
+	add("prize", previous.Prize, r.Prize)
+	add("attributes", emptyAttributes(previous.Attributes), emptyAttributes(r.Attributes))
+	add("latitude", floatValue(previous.Latitude), floatValue(r.Latitude))
+	add("longitude", floatValue(previous.Longitude), floatValue(r.Longitude))
+	return changes
+}
+
+
+// AfterCreate stores the first revision of every new item
+func (i *Item) AfterCreate(tx *gorm.DB) error {
+	revision := RevisionOf(i)
+	revision.Revision = 1
+	i.Revision = 1
+	return tx.Create(&revision).Error
+}
+
+
+func emptyAttributes(attributes Attributes) Attributes {
+	if attributes == nil {
+		return Attributes{}
+	}
+	return attributes
+}
+
+
+func floatValue(value *float64) interface{} {
+	if value == nil {
+		return nil
+	}
+	return *value
+}
@@ -0,0 +1,40 @@
+package models
+
+
+const (
+	UserRole  = "USER"
+	AdminRole = "ADMIN"
+)
+
+
+type Permission string
+
+const (
+	ManageCategories Permission = "categories:manage"
+	ModerateListings Permission = "listings:moderate"
+	ManageUsers      Permission = "users:manage"
+)
+
+
+// RolePermissions lists what each role is allowed to do beyond
+// acting on its own resources
+var RolePermissions = map[string][]Permission{
+	UserRole:  {},
+	AdminRole: {ManageCategories, ModerateListings, ManageUsers},
+}
+
+
+func IsValidRole(role string) bool {
+	_, ok := RolePermissions[role]
+	return ok
+}
+
+
+func HasPermission(role string, permission Permission) bool {
+	for _, p := range RolePermissions[role] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"time"
+)
+
+
+// How often a saved search alerts its owner about new matches
+const (
+	InstantAlerts = "INSTANT"
+	DailyAlerts   = "DAILY"
+)
+
+
+// DigestInterval is how long daily digest matches are collected before being sent
+const DigestInterval = 24 * time.Hour
+
+
+// SavedSearch is a search a user wants to hear about when new items match it
+type SavedSearch struct {
+	Base
+	UserId        uint        `json:"-" gorm:"not null;index"`
+	Name          string      `json:"name"`
+	CategoryName  string      `json:"categoryName"`
+	CategoryId    *uint       `json:"-"`
+	Keywords      string      `json:"keywords"`
+	MinPrize      *float64    `json:"minPrize" gorm:"type:numeric(19,2)"`
+	MaxPrize      *float64    `json:"maxPrize" gorm:"type:numeric(19,2)"`
+	Frequency     string      `json:"frequency" gorm:"default:INSTANT;index"`
+	LastAlertedAt time.Time   `json:"lastAlertedAt"`
+}
+
+
+// IsValidFrequency reports whether frequency is a known alert frequency
+func IsValidFrequency(frequency string) bool {
+	return frequency == InstantAlerts || frequency == DailyAlerts
+}
+
+
+type ISavedSearchRepository interface {
+	CreateSavedSearch(search *SavedSearch) (*SavedSearch, error)
+	GetSavedSearches(userId uint, page PageRequest) ([]SavedSearch, *Page, error)
+	DeleteSavedSearch(userId, searchId uint) error
+	// GetInstantMatches returns the instant saved searches of other users that item matches
+	GetInstantMatches(item *Item) ([]SavedSearch, error)
+	// GetDueDigests returns the daily saved searches last alerted before cutoff
+	GetDueDigests(cutoff time.Time) ([]SavedSearch, error)
+	// GetNewMatches returns the items registered since the search last alerted that match it
+	GetNewMatches(search *SavedSearch, limit int) ([]Item, error)
+	MarkAlerted(searchId uint, at time.Time) error
+}
+
+
+type ISavedSearchService interface {
+	IItemObserver
+	CreateSavedSearch(search *SavedSearch) (*SavedSearch, error)
+	GetSavedSearches(userId uint, page PageRequest) ([]SavedSearch, *Page, error)
+	DeleteSavedSearch(userId, searchId uint) error
+	SendDailyDigests() error
+}
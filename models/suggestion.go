@@ -0,0 +1,40 @@
+package models
+
+import (
+	"context"
+	"time"
+)
+
+
+const (
+	// MinSuggestionPrefix is the shortest prefix worth looking up
+	MinSuggestionPrefix = 2
+
+	DefaultSuggestionLimit = 5
+	MaxSuggestionLimit     = 10
+
+	// SuggestionTimeout is the latency budget of one autocomplete lookup.
+	// Slower lookups return no suggestions rather than hold up typing.
+	SuggestionTimeout = 150 * time.Millisecond
+)
+
+
+// Suggestion is a lightweight autocomplete result
+type Suggestion struct {
+	ID      uint    `json:"id"`
+	Value   string  `json:"value"`
+}
+
+
+type ISuggestionRepository interface {
+	SuggestItemNames(ctx context.Context, prefix string, limit int) ([]Suggestion, error)
+	SuggestCategories(ctx context.Context, prefix string, limit int) ([]Suggestion, error)
+	SuggestUserNames(ctx context.Context, prefix string, limit int) ([]Suggestion, error)
+}
+
+
+type ISuggestionService interface {
+	SuggestItemNames(prefix string, limit int) ([]Suggestion, error)
+	SuggestCategories(prefix string, limit int) ([]Suggestion, error)
+	SuggestUserNames(prefix string, limit int) ([]Suggestion, error)
+}
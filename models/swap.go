@@ -9,6 +9,8 @@ type SwapRequest struct {
 	Item2Id				uint 			`json:"item2Id" gorm:"not null"`
 	OwnerId 			uint 			`json:"ownerId" gorm:"not null"` //Owner of target item item2
 	InitiatorId         uint 			`json:"initiatorId" gorm:not null"` //Initiator of swap request and owner of item1
+	Item1Revision       int             `json:"item1Revision"` // Revisions of the items when the request was made
+	Item2Revision       int             `json:"item2Revision"`
 	Status				string 			`json:"status" gorm:"default: PENDING"` //PENDING, APPROVED, REJECTED
 	CompletionStatus    string			`json:"completionStatus"`
 }
@@ -56,22 +58,23 @@ type UserDetails struct {
 
 
 type ISwapRepository interface {
+	GetSwapRequestById(id uint) (*SwapRequest, error)
 	InitiateSwapRequest(item1Id, item2Id, initiatorId uint) (*SwapRequest, error)
-	GetPendingSwapRequests(ownerId int, limit, page int) ([]EnrichedSwapRequest, error)
+	GetPendingSwapRequests(ownerId int, page PageRequest) ([]EnrichedSwapRequest, *Page, error)
 	RejectSwapRequest(ownerId, swapId int) error
 	AcceptSwapRequest(ownerId, swapId int) (string, error)
 	CompleteSwapRequest(ownerId uint, amount float64, swapId uint) (string, error)
 	GetIncompleteSwapByInitiatorId(initiatorId, itemId int) (IncompleteSwaps, error)
-	GetAllIncompleteSwapByOwnerId(ownerId, limit, page int) ([]IncompleteSwaps, error)
+	GetAllIncompleteSwapByOwnerId(ownerId int, page PageRequest) ([]IncompleteSwaps, *Page, error)
 }
 
 
 type ISwapService interface {
 	InitiateSwapRequest(item1Id, item2Id, initiatorId uint) (*SwapRequest, error)
-	GetPendingSwapRequests(ownerId int, limit, page int) ([]EnrichedSwapRequest, error)
-	RejectSwapRequest(ownerId, swapId int) error
-	AcceptSwapRequest(ownerId, swapId int) (string, error)
-	CompleteSwapRequest(ownerId uint, amount float64, swapId uint) (string, error)
+	GetPendingSwapRequests(ownerId int, page PageRequest) ([]EnrichedSwapRequest, *Page, error)
+	RejectSwapRequest(actor Actor, swapId int) error
+	AcceptSwapRequest(actor Actor, swapId int) (string, error)
+	CompleteSwapRequest(actor Actor, amount float64, swapId uint) (string, error)
 	GetIncompleteSwapByInitiatorId(initiatorId, itemId int) (IncompleteSwaps, error)
-	GetAllIncompleteSwapByOwnerId(ownerId, limit, page int) ([]IncompleteSwaps, error)
+	GetAllIncompleteSwapByOwnerId(ownerId int, page PageRequest) ([]IncompleteSwaps, *Page, error)
 }
\ No newline at end of file
@@ -20,9 +20,11 @@ type Transactions struct {
 	PhoneNumber 	string 		`json:"phoneNumber"`
 	OwnerId         uint        `gorm:"not null;index" json: "-"`
 	ItemId 			uint 		`json: "_"`
+	ItemRevision    int         `json:"itemRevision"` // Revision of the item that was bought or swapped
 	ItemName        string      `json:"itemName"`
 	Bought          bool        `json:"bought"`
 	Swapped         bool        `json:"swapped"`
+	Claimed         bool        `json:"claimed"` // Received for free from a give-away
 	AmountPaid      float64     `json: "amount:"amountPaid"`
 	BalanceAvailabe float64     `json:"balanceAvailable"`
 	BalanceOwed     float64		`json:"balanceOwed"`
@@ -41,6 +43,7 @@ type User struct {
 	Gender                string 	`json: "gender"`
 	Password			  string 	`json: "-"`
 	Location              string 	`json:"location"`
+	Role                  string    `json:"role" gorm:"default:USER"`
 	TotpEnabled           bool      `json:"totpEnabled" gorm:"type:bool;default:false"`
 	TotpSecret            string    `json:"_"`
 	OneTimePassword       string    `json:"-"`
@@ -61,12 +64,13 @@ type IUserRepository interface {
 	FindUserByEmailOrUsername(email string) (*User, error)
 	InvalidateOneTimePassword(user *User) error
 	CreateOneTimePassword(user *User, password string, expiry time.Time) error
-	Search(term string, limit, page int) ([]User, error)
+	Search(term string, page PageRequest) ([]User, *Page, error)
 	UpdateUser(user User) error
 	UpdateUserTOTP(user User, totpSecret string, totpEnabled bool) error
 	UpdatePassword(userId uint, password string) error
-	GetUserTransactions(userId, limit, page int) ([]Transactions, error)
+	GetUserTransactions(userId int, page PageRequest) ([]Transactions, *Page, error)
 	GetUserByItemId(id int) (*User, error)
+	UpdateUserRole(userId uint, role string) error
 }
 
 type IUserService interface {
@@ -78,7 +82,7 @@ type IUserService interface {
 	LoginWithOneTimePassword(email, code string) (*User, error)
 	UpdatePassword(userId uint, password string) error
 	ConfirmPassword(userId uint, password string) (*User, error)
-	Search(term string, limit, page int) ([]User, error)
+	Search(term string, page PageRequest) ([]User, *Page, error)
 	FindUserByEmailOrUsername(email string) (*User, error)
 	FindUserByPhoneNumber(phoneNumber string) (*User, error)
 	UpdateUser(user User) error
@@ -88,8 +92,10 @@ type IUserService interface {
 	VerifyTOTP(userId int, verifyTOTP VerifyTOTPRequest) error
 	DisableTOTP(userId int) error
 	EnableTOTP(userId int) error
-	GetUserTransactions(userId, limit, page int) ([]Transactions, error)
+	GetUserTransactions(userId int, page PageRequest) ([]Transactions, *Page, error)
 	GetUserByItemId(id int) (*User, error)
+	UpdateUserRole(userId int, role string) error
+	BootstrapAdmins(emails []string)
 }
 
 func (user *User) HashPassword(password string) error {
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+)
+
+
+// Watch records that a user is watching an item. Unwatching deletes the row,
+// so it has no soft delete.
+type Watch struct {
+	ID          uint       `gorm:"primarykey" json:"id"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	UserId      uint       `json:"-" gorm:"not null;uniqueIndex:idx_watches_user_item"`
+	ItemId      uint       `json:"itemId" gorm:"not null;uniqueIndex:idx_watches_user_item;index"`
+}
+
+
+type IWatchlistRepository interface {
+	WatchItem(userId, itemId uint) error
+	UnwatchItem(userId, itemId uint) error
+	GetWatchedItems(userId uint, page PageRequest) ([]Item, *Page, error)
+	GetWatcherIds(itemId uint) ([]uint, error)
+}
+
+
+type IWatchlistService interface {
+	IItemObserver
+	WatchItem(userId, itemId uint) error
+	UnwatchItem(userId, itemId uint) error
+	GetWatchedItems(userId uint, page PageRequest) ([]Item, *Page, error)
+}
@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"log"
+	"time"
+
+	"swap/apperrors"
+	"swap/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+
+type analyticsRepository struct {
+	DB *gorm.DB
+}
+
+
+func NewAnalyticsRepository(db *gorm.DB) models.IAnalyticsRepository {
+	return &analyticsRepository{
+		DB: db,
+	}
+}
+
+
+// RecordView stores a view unless the viewer already looked at the item that day
+func (r *analyticsRepository) RecordView(itemId uint, viewerKey string, day time.Time) error {
+	view := &models.ItemView{ItemId: itemId, ViewerKey: viewerKey, Day: day}
+
+	if err := r.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(view).Error; err != nil {
+		log.Printf("Could not record view of item %d: %v\n", itemId, err)
+		return apperrors.NewInternal()
+	}
+	return nil
+}
+
+
+// AggregateDailyStats recounts views, new watches and swap requests for every
+// day since the given one. Recounting whole days keeps the job safe to repeat.
+func (r *analyticsRepository) AggregateDailyStats(since time.Time) error {
+	err := r.DB.Exec(`INSERT INTO item_daily_stats (item_id, day, views, watches, swap_requests)
+		SELECT item_id, day, sum(views), sum(watches), sum(swap_requests) FROM (
+			SELECT item_id, day, count(*) AS views, 0 AS watches, 0 AS swap_requests
+				FROM item_views WHERE day >= @since GROUP BY item_id, day
+			UNION ALL
+			SELECT item_id, created_at::date, 0, count(*), 0
+				FROM watches WHERE created_at::date >= @since GROUP BY item_id, created_at::date
+			UNION ALL
+			SELECT item2_id, created_at::date, 0, 0, count(*)
+				FROM swap_requests WHERE deleted_at IS NULL AND created_at::date >= @since GROUP BY item2_id, created_at::date
+		) counts
+		GROUP BY item_id, day
+		ON CONFLICT (item_id, day) DO UPDATE SET
+			views = EXCLUDED.views, watches = EXCLUDED.watches, swap_requests = EXCLUDED.swap_requests`,
+		map[string]interface{}{"since": since}).Error
+
+	if err != nil {
+		log.Printf("Could not aggregate item stats: %v\n", err)
+		return apperrors.NewInternal()
+	}
+	return nil
+}
+
+
+func (r *analyticsRepository) PruneViews(before time.Time) error {
+	if err := r.DB.Where("day < ?", before).Delete(&models.ItemView{}).Error; err != nil {
+		return apperrors.NewInternal()
+	}
+	return nil
+}
+
+
+func (r *analyticsRepository) GetDailyStats(itemId uint, from, to time.Time) ([]models.ItemDailyStat, error) {
+	var stats []models.ItemDailyStat
+
+	err := r.DB.Where("item_id = ? AND day BETWEEN ? AND ?", itemId, from, to).Order("day").Find(&stats).Error
+	if err != nil {
+		return stats, apperrors.NewInternal()
+	}
+	return stats, nil
+}
+
+
+func (r *analyticsRepository) CountWatchers(itemId uint) (int64, error) {
+	var watchers int64
+
+	if err := r.DB.Model(&models.Watch{}).Where("item_id = ?", itemId).Count(&watchers).Error; err != nil {
+		return 0, apperrors.NewInternal()
+	}
+	return watchers, nil
+}
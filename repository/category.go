@@ -3,7 +3,12 @@ package repository
 import (
 	"swap/models"
 	"swap/apperrors"
+	"swap/utils"
+	"errors"
+	"fmt"
 	"log"
+	"strconv"
+	"strings"
 
 	"gorm.io/gorm"
 )
@@ -20,16 +25,25 @@ func NewCategoryRepository(db *gorm.DB) models.ICategoryRepository {
 }
 
 
-func (r *categoryRepository) CreateCategory(name string)(*models.Category, error){
+func (r *categoryRepository) CreateCategory(name string, parentSlug string)(*models.Category, error){
 	category := &models.Category{}
 	
 	result := r.DB.Where("name = ?", name).Find(&category)
-	if result.Error != nil {
+	if result.Error != nil || result.RowsAffected > 0 {
 		log.Print("Category already exists")
 		return nil, apperrors.NewBadRequest("Category already exists")
 	}
 
+	if parentSlug != "" {
+		parent, err := findCategory(r.DB, parentSlug)
+		if err != nil {
+			return nil, apperrors.NewBadRequest("Parent category does not exist")
+		}
+		category.ParentId = &parent.ID
+	}
+
 	category.Name = name
+	category.Slug = utils.Slugify(name)
 	if err := r.DB.Create(&category).Error; err != nil {
 		return nil, apperrors.NewBadRequest("Unable to create category")
 	}
@@ -37,29 +51,43 @@ func (r *categoryRepository) CreateCategory(name string)(*models.Category, error
 }
 
 
-func (r *categoryRepository) DeleteCategory(category string) error {
-	result := r.DB.Model(&models.Item{}).
-			  Where("category_name = ?", category).
-			  Updates(map[string]interface{}{
-				"category_name": nil,
-				"category_id": nil,
-			  })
-	
-	if result.Error != nil {
-		return apperrors.NewBadRequest("Unable to delete category")
+func (r *categoryRepository) DeleteCategory(name string) error {
+	category, err := findCategory(r.DB, name)
+	if err != nil {
+		return apperrors.NewBadRequest("Invalid category")
 	}
 
-	if err := r.DB.Where("name = ?", category).Delete(&models.Category{}).Error; err != nil {
-		return apperrors.NewBadRequest("Unable to delete category")
-	}
-	return nil
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Item{}).
+				  Where("category_id = ?", category.ID).
+				  Updates(map[string]interface{}{
+					"category_name": nil,
+					"category_id": nil,
+				  })
+		
+		if result.Error != nil {
+			return apperrors.NewBadRequest("Unable to delete category")
+		}
+
+		// Children move up to the deleted category's parent instead of being orphaned
+		if err := tx.Model(&models.Category{}).Where("parent_id = ?", category.ID).
+			Update("parent_id", category.ParentId).Error; err != nil {
+			return apperrors.NewBadRequest("Unable to delete category")
+		}
+
+		if err := tx.Delete(&category).Error; err != nil {
+			return apperrors.NewBadRequest("Unable to delete category")
+		}
+		return nil
+	})
 }
 
 
 func (r *categoryRepository) GetAllValidCategories() ([]models.Category, error) {
 	var categories []models.Category
 	
-	if err := r.DB.Select("name", "ban", "id").Where("ban = ?", false).Find(&categories).Error; err != nil {
+	if err := r.DB.Select("name", "slug", "ban", "id", "parent_id").Where("ban = ?", false).
+		Order("name").Find(&categories).Error; err != nil {
 		return categories, apperrors.NewInternal()
 	}
 	return categories, nil
@@ -112,19 +140,265 @@ func (r *categoryRepository) CheckStatus(name string) (bool, error) {
 }
 
 
-func (r *categoryRepository) GetAllItemsInCategory(id, limit, page int) ([]models.Item, error) {
+func (r *categoryRepository) GetAllItemsInCategory(id int, request models.PageRequest) ([]models.Item, *models.Page, error) {
 	var items []models.Item
 	category := &models.Category{}
 
-	if err := r.DB.Where("id = ?", id).Find(&category).Error; err != nil {
+	if err := r.DB.Where("id = ?", id).First(&category).Error; err != nil {
 		log.Print("Could not find category")
-		return items, apperrors.NewBadRequest("Could not find category")
+		return items, nil, apperrors.NewBadRequest("Could not find category")
 	}
 
-	if err := r.DB.Where("category_id = ? AND sold = ?", category.ID, false).Find(&items).Error; err != nil {
+	categoryIds, err := descendantCategoryIds(r.DB, category.ID)
+	if err != nil {
+		log.Print("Could not find subcategories")
+		return items, nil, apperrors.NewInternal()
+	}
+
+	page, err := findPage(r.DB.Where("category_id IN ? AND sold = ? AND expired = ?", categoryIds, false, false), "id", request, &items)
+	if err != nil {
 		log.Print("Could not find items")
-		return items, apperrors.NewInternal()
+		return items, nil, err
+	}
+
+	return items, page, nil
+}
+
+
+func (r *categoryRepository) GetCategory(nameOrSlug string) (*models.Category, error) {
+	category, err := findCategory(r.DB, nameOrSlug)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperrors.NewNotFound("category", nameOrSlug)
+		}
+		return nil, apperrors.NewInternal()
 	}
+	return category, nil
+}
+
+
+func (r *categoryRepository) UpdateAttributeSchema(id int, schema models.AttributeSchema) error {
+	result := r.DB.Model(&models.Category{}).Where("id = ?", id).Update("attribute_schema", schema)
 
-	return items, nil
-}
\ No newline at end of file
+	if result.Error != nil {
+		log.Printf("Could not update attribute schema of category %d: %v\n", id, result.Error)
+		return apperrors.NewInternal()
+	}
+
+	if result.RowsAffected == 0 {
+		return apperrors.NewNotFound("category ID", strconv.Itoa(id))
+	}
+	return nil
+}
+
+
+// errDryRun rolls back a maintenance transaction once its report is complete
+var errDryRun = errors.New("dry run")
+
+
+// runMaintenance executes change in a transaction and, for dry runs, rolls it
+// back so the report reflects exactly what would have been affected
+func (r *categoryRepository) runMaintenance(dryRun bool, change func(tx *gorm.DB, report *models.CategoryChangeReport) error) (*models.CategoryChangeReport, error) {
+	report := &models.CategoryChangeReport{DryRun: dryRun}
+
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := change(tx, report); err != nil {
+			return err
+		}
+		if dryRun {
+			return errDryRun
+		}
+		return nil
+	})
+
+	if err != nil && !errors.Is(err, errDryRun) {
+		log.Printf("Category maintenance failed: %v\n", err)
+		return nil, apperrors.GetAppError(err, apperrors.ServerError)
+	}
+	return report, nil
+}
+
+
+func (r *categoryRepository) RenameCategory(name, newName string, dryRun bool) (*models.CategoryChangeReport, error) {
+	return r.runMaintenance(dryRun, func(tx *gorm.DB, report *models.CategoryChangeReport) error {
+		category, err := findCategory(tx, name)
+		if err != nil {
+			return apperrors.NewNotFound("category", name)
+		}
+
+		var existing int64
+		if err := tx.Model(&models.Category{}).Where("name = ? AND id <> ?", newName, category.ID).Count(&existing).Error; err != nil {
+			return err
+		}
+		if existing > 0 {
+			return apperrors.NewConflict("category", newName)
+		}
+
+		// The slug is kept so links to the category survive a rename
+		if err := tx.Model(&category).Update("name", newName).Error; err != nil {
+			return err
+		}
+
+		var items []models.Item
+		if err := tx.Where("category_id = ?", category.ID).Find(&items).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(&models.Item{}).Where("category_id = ?", category.ID).Update("category_name", newName).Error; err != nil {
+			return err
+		}
+
+		// The new name is part of what buyers saw, so it becomes a revision
+		for i := range items {
+			items[i].CategoryName = newName
+			if err := recordRevision(tx, &items[i]); err != nil {
+				return err
+			}
+		}
+		report.Items = int64(len(items))
+		return nil
+	})
+}
+
+
+func (r *categoryRepository) MergeCategory(source, target string, dryRun bool) (*models.CategoryChangeReport, error) {
+	return r.runMaintenance(dryRun, func(tx *gorm.DB, report *models.CategoryChangeReport) error {
+		from, err := findCategory(tx, source)
+		if err != nil {
+			return apperrors.NewNotFound("category", source)
+		}
+
+		into, err := findCategory(tx, target)
+		if err != nil {
+			return apperrors.NewNotFound("category", target)
+		}
+
+		if from.ID == into.ID {
+			return apperrors.NewBadRequest("Cannot merge a category into itself")
+		}
+
+		// Walk up from the target rather than down from the source: the
+		// downward walk skips banned subtrees, which would hide a cycle
+		ancestors, err := ancestorCategoryIds(tx, into.ID)
+		if err != nil {
+			return err
+		}
+		for _, id := range ancestors {
+			if id == from.ID {
+				return apperrors.NewBadRequest("Cannot merge a category into one of its subcategories")
+			}
+		}
+
+		if err := moveItems(tx, tx.Where("category_id = ?", from.ID), into, dryRun, report); err != nil {
+			return err
+		}
+
+		result := tx.Model(&models.Category{}).Where("parent_id = ?", from.ID).Update("parent_id", into.ID)
+		if result.Error != nil {
+			return result.Error
+		}
+		report.Subcategories = result.RowsAffected
+
+		return tx.Delete(&from).Error
+	})
+}
+
+
+func (r *categoryRepository) ReassignItems(itemIds []uint, target string, dryRun bool) (*models.CategoryChangeReport, error) {
+	return r.runMaintenance(dryRun, func(tx *gorm.DB, report *models.CategoryChangeReport) error {
+		into, err := findCategory(tx, target)
+		if err != nil {
+			return apperrors.NewNotFound("category", target)
+		}
+
+		return moveItems(tx, tx.Where("id IN ?", itemIds), into, dryRun, report)
+	})
+}
+
+
+// moveItems moves the items matched by query into a category, recording a
+// revision for each. Banned categories take no items, and items whose
+// attributes break the category's schema are listed in the report instead;
+// outside a dry run they stop the whole change.
+func moveItems(tx *gorm.DB, query *gorm.DB, into *models.Category, dryRun bool, report *models.CategoryChangeReport) error {
+	if into.Ban {
+		return apperrors.NewBadRequest(fmt.Sprintf("Cannot move items into banned category %s", into.Name))
+	}
+
+	var items []models.Item
+	if err := query.Find(&items).Error; err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		if err := into.AttributeSchema.Validate(item.Attributes); err != nil {
+			report.Problems = append(report.Problems, models.CategoryItemProblem{ItemId: item.ID, Problem: err.Error()})
+		}
+	}
+	if len(report.Problems) > 0 && !dryRun {
+		return apperrors.NewBadRequest(fmt.Sprintf("%d items do not fit the attributes of %s; run a dry run to list them",
+			len(report.Problems), into.Name))
+	}
+
+	for i := range items {
+		item := &items[i]
+		err := tx.Model(&models.Item{}).Where("id = ?", item.ID).
+			Updates(map[string]interface{}{"category_id": into.ID, "category_name": into.Name}).Error
+		if err != nil {
+			return err
+		}
+
+		item.CategoryId = &into.ID
+		item.CategoryName = into.Name
+		if err := recordRevision(tx, item); err != nil {
+			return err
+		}
+	}
+	report.Items = int64(len(items))
+	return nil
+}
+
+
+// findCategory resolves a category by its display name or its slug
+func findCategory(db *gorm.DB, nameOrSlug string) (*models.Category, error) {
+	category := &models.Category{}
+
+	err := db.Where("name = ? OR slug = ?", strings.ToUpper(nameOrSlug), utils.Slugify(nameOrSlug)).
+		First(&category).Error
+	if err != nil {
+		return nil, err
+	}
+	return category, nil
+}
+
+
+// descendantCategoryIds returns the ID of the category together with the IDs
+// of every category below it, so searching a parent also finds its children's items
+func descendantCategoryIds(db *gorm.DB, categoryId uint) ([]uint, error) {
+	var ids []uint
+
+	err := db.Raw(`WITH RECURSIVE tree AS (
+			SELECT id FROM categories WHERE id = ? AND deleted_at IS NULL
+			UNION
+			SELECT c.id FROM categories c JOIN tree t ON c.parent_id = t.id
+			WHERE c.deleted_at IS NULL AND c.ban = false
+		) SELECT id FROM tree`, categoryId).Scan(&ids).Error
+
+	return ids, err
+}
+
+
+// ancestorCategoryIds returns the ID of a category together with those of all
+// the categories above it
+func ancestorCategoryIds(db *gorm.DB, categoryId uint) ([]uint, error) {
+	var ids []uint
+
+	err := db.Raw(`WITH RECURSIVE tree AS (
+			SELECT id, parent_id FROM categories WHERE id = ? AND deleted_at IS NULL
+			UNION
+			SELECT c.id, c.parent_id FROM categories c JOIN tree t ON c.id = t.parent_id
+			WHERE c.deleted_at IS NULL
+		) SELECT id FROM tree`, categoryId).Scan(&ids).Error
+
+	return ids, err
+}
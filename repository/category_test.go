@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"os"
+	"testing"
+
+	"swap/datasources"
+	"swap/models"
+
+	"gorm.io/gorm"
+)
+
+
+// testDB opens the database named by the DATABASE_* variables inside a
+// transaction that is rolled back when the test ends. Tests are skipped when
+// no database is configured.
+func testDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	if os.Getenv("DATABASE_HOST") == "" {
+		t.Skip("DATABASE_HOST is not set")
+	}
+
+	ds, err := datasources.InitDS()
+	if err != nil {
+		t.Fatalf("opening database: %v", err)
+	}
+
+	tx := ds.DB.Begin()
+	t.Cleanup(func() { tx.Rollback() })
+	return tx
+}
+
+
+func createTestCategory(t *testing.T, db *gorm.DB, name string, parent *models.Category, ban bool) *models.Category {
+	t.Helper()
+	category := &models.Category{Name: name, Slug: name, Ban: ban}
+	if parent != nil {
+		category.ParentId = &parent.ID
+	}
+	if err := db.Create(category).Error; err != nil {
+		t.Fatalf("creating category %s: %v", name, err)
+	}
+	return category
+}
+
+
+func TestMergeCategoryRefusesSubcategoryBelowBannedOne(t *testing.T) {
+	db := testDB(t)
+	repo := NewCategoryRepository(db)
+
+	a := createTestCategory(t, db, "MERGE-TEST-A", nil, false)
+	b := createTestCategory(t, db, "MERGE-TEST-B", a, true)
+	c := createTestCategory(t, db, "MERGE-TEST-C", b, false)
+
+	if _, err := repo.MergeCategory(a.Name, c.Name, false); err == nil {
+		t.Fatalf("merging %s into its grandchild %s succeeded", a.Name, c.Name)
+	}
+
+	var parent models.Category
+	if err := db.First(&parent, b.ID).Error; err != nil {
+		t.Fatalf("loading %s: %v", b.Name, err)
+	}
+	if parent.ParentId == nil || *parent.ParentId != a.ID {
+		t.Errorf("%s was re-parented to %v, want %d", b.Name, parent.ParentId, a.ID)
+	}
+}
@@ -0,0 +1,270 @@
+package repository
+
+import (
+	"errors"
+	"log"
+	"strconv"
+	"time"
+
+	"swap/apperrors"
+	"swap/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+
+type claimRepository struct {
+	DB *gorm.DB
+}
+
+func NewClaimRepository(db *gorm.DB) models.IClaimRepository {
+	return &claimRepository{
+		DB: db,
+	}
+}
+
+
+// lockGiveaway loads a give-away item and locks its row, so claims on the same
+// item are handled one at a time and first-come-first-served stays fair
+func lockGiveaway(tx *gorm.DB, itemId uint) (*models.Item, error) {
+	item := &models.Item{}
+
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", itemId).First(item).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperrors.NewNotFound("item", strconv.Itoa(int(itemId)))
+		}
+		return nil, err
+	}
+
+	if !item.Giveaway {
+		return nil, apperrors.NewBadRequest("Item is not a give-away")
+	}
+	if item.Sold {
+		return nil, apperrors.NewBadRequest("Item has already been given away")
+	}
+	return item, nil
+}
+
+
+// lockOpenGiveaway is lockGiveaway for changes that need the listing to still
+// be running. Claims can be withdrawn after it expires, but not made or chosen.
+func lockOpenGiveaway(tx *gorm.DB, itemId uint) (*models.Item, error) {
+	item, err := lockGiveaway(tx, itemId)
+	if err != nil {
+		return nil, err
+	}
+
+	if item.Expired {
+		return nil, apperrors.NewBadRequest("Listing has expired")
+	}
+	return item, nil
+}
+
+
+// promoteNextClaim makes the oldest waiting claim the chosen one
+func promoteNextClaim(tx *gorm.DB, itemId uint) (*models.Claim, error) {
+	next := &models.Claim{}
+
+	result := tx.Where("item_id = ? AND status = ?", itemId, models.ClaimWaiting).Order("created_at, id").Limit(1).Find(next)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+
+	if err := tx.Model(next).Update("status", models.ClaimChosen).Error; err != nil {
+		return nil, err
+	}
+	return next, nil
+}
+
+
+func (r *claimRepository) CreateClaim(itemId, userId uint, message string) (*models.Claim, error) {
+	claim := &models.Claim{ItemId: itemId, UserId: userId, Status: models.ClaimWaiting, Message: message}
+
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		item, err := lockOpenGiveaway(tx, itemId)
+		if err != nil {
+			return err
+		}
+
+		if item.OwnerId == userId {
+			return apperrors.NewBadRequest("Cannot claim your own item")
+		}
+
+		var existing int64
+		if err := tx.Model(&models.Claim{}).Where("item_id = ? AND user_id = ?", itemId, userId).Count(&existing).Error; err != nil {
+			return err
+		}
+		if existing > 0 {
+			return apperrors.NewConflict("claim on item", strconv.Itoa(int(itemId)))
+		}
+
+		if item.ClaimMode == models.FirstComeClaims {
+			var chosen int64
+			if err := tx.Model(&models.Claim{}).Where("item_id = ? AND status = ?", itemId, models.ClaimChosen).Count(&chosen).Error; err != nil {
+				return err
+			}
+			if chosen == 0 {
+				claim.Status = models.ClaimChosen
+			}
+		}
+
+		return tx.Create(claim).Error
+	})
+
+	if err != nil {
+		log.Printf("Could not claim item %d for user %d: %v\n", itemId, userId, err)
+		return nil, apperrors.GetAppError(err, apperrors.ServerError)
+	}
+	return claim, nil
+}
+
+
+func (r *claimRepository) WithdrawClaim(itemId, userId uint) (*models.Claim, error) {
+	var promoted *models.Claim
+
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		item, err := lockGiveaway(tx, itemId)
+		if err != nil {
+			return err
+		}
+
+		claim := &models.Claim{}
+		if err := tx.Where("item_id = ? AND user_id = ?", itemId, userId).First(claim).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return apperrors.NewNotFound("claim on item", strconv.Itoa(int(itemId)))
+			}
+			return err
+		}
+
+		if err := tx.Delete(claim).Error; err != nil {
+			return err
+		}
+
+		// Owners picking by hand choose again, otherwise the queue moves up
+		// while the listing is still running
+		if claim.Status == models.ClaimChosen && item.ClaimMode == models.FirstComeClaims && !item.Expired {
+			promoted, err = promoteNextClaim(tx, itemId)
+			return err
+		}
+		return nil
+	})
+
+	if err != nil {
+		log.Printf("Could not withdraw claim of user %d on item %d: %v\n", userId, itemId, err)
+		return nil, apperrors.GetAppError(err, apperrors.ServerError)
+	}
+	return promoted, nil
+}
+
+
+func (r *claimRepository) GetClaims(itemId uint, request models.PageRequest) ([]models.Claim, *models.Page, error) {
+	var claims []models.Claim
+
+	page, err := findPage(r.DB.Where("item_id = ?", itemId), "id", request, &claims)
+	if err != nil {
+		return claims, nil, err
+	}
+	return claims, page, nil
+}
+
+
+func (r *claimRepository) ChooseClaim(itemId, claimId uint) (*models.Claim, error) {
+	claim := &models.Claim{}
+
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		if _, err := lockOpenGiveaway(tx, itemId); err != nil {
+			return err
+		}
+
+		if err := tx.Where("id = ? AND item_id = ?", claimId, itemId).First(claim).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return apperrors.NewNotFound("claim", strconv.Itoa(int(claimId)))
+			}
+			return err
+		}
+
+		// The previously chosen claimant goes back into the queue
+		if err := tx.Model(&models.Claim{}).Where("item_id = ? AND status = ? AND id <> ?", itemId, models.ClaimChosen, claimId).
+			Update("status", models.ClaimWaiting).Error; err != nil {
+			return err
+		}
+
+		claim.Status = models.ClaimChosen
+		return tx.Model(claim).Update("status", models.ClaimChosen).Error
+	})
+
+	if err != nil {
+		log.Printf("Could not choose claim %d on item %d: %v\n", claimId, itemId, err)
+		return nil, apperrors.GetAppError(err, apperrors.ServerError)
+	}
+	return claim, nil
+}
+
+
+// CompleteClaim hands the item to the chosen claimant, declines everyone else
+// and records the hand-over as a zero-value transaction
+func (r *claimRepository) CompleteClaim(itemId uint) (*models.ClaimOutcome, error) {
+	outcome := &models.ClaimOutcome{}
+
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		item, err := lockOpenGiveaway(tx, itemId)
+		if err != nil {
+			return err
+		}
+
+		claim := &outcome.Completed
+		if err := tx.Where("item_id = ? AND status = ?", itemId, models.ClaimChosen).First(claim).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return apperrors.NewBadRequest("No recipient has been chosen")
+			}
+			return err
+		}
+
+		owner := &models.User{}
+		if err := tx.Where("id = ?", item.OwnerId).First(owner).Error; err != nil {
+			return apperrors.NewBadRequest("Unable to find owner")
+		}
+
+		if err := tx.Model(&models.Claim{}).Where("item_id = ? AND id <> ?", itemId, claim.ID).
+			Pluck("user_id", &outcome.DeclinedUserIds).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(&models.Claim{}).Where("item_id = ? AND id <> ?", itemId, claim.ID).
+			Update("status", models.ClaimDeclined).Error; err != nil {
+			return err
+		}
+
+		claim.Status = models.ClaimCompleted
+		if err := tx.Model(claim).Update("status", models.ClaimCompleted).Error; err != nil {
+			return err
+		}
+
+		transaction := models.Transactions{
+			Name:         owner.Name,
+			Email:        owner.Email,
+			PhoneNumber:  owner.PhoneNumber,
+			OwnerId:      claim.UserId,
+			ItemId:       item.ID,
+			ItemRevision: item.Revision,
+			ItemName:     item.Name,
+			Claimed:      true,
+		}
+		if err := tx.Create(&transaction).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(item).Updates(models.Item{Sold: true, SoldAt: time.Now().Truncate(time.Second)}).Error
+	})
+
+	if err != nil {
+		log.Printf("Could not complete give-away of item %d: %v\n", itemId, err)
+		return nil, apperrors.GetAppError(err, apperrors.ServerError)
+	}
+	return outcome, nil
+}
@@ -3,13 +3,15 @@ package repository
 import (
 	"swap/models"
 	"swap/apperrors"
-	"swap/utils"
 
+	"fmt"
 	"log"
 	"errors"
 	"strconv"
+	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 
@@ -24,71 +26,197 @@ func NewImageRepository(db *gorm.DB) models.IImageRepository {
 	}
 }
 
-func (r *imageRepository) UploadImage(itemId int, folderName, fileName string) error{
-	item := &models.Item{}
-	image := &models.Image{}
+// UploadImage saves an image record once the item is known to have room for
+// it. The item row is locked so concurrent uploads are counted one at a time.
+func (r *imageRepository) UploadImage(image *models.Image) error{
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		item := &models.Item{}
 
-	if err := r.DB.Where("id = ?", itemId).First(&item).Error; err != nil {
-		log.Printf("Could not find item with ID: %d\n", itemId)
-		return apperrors.NewBadRequest("Could not find item with provided ID")
-	}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", image.ItemId).First(&item).Error; err != nil {
+			log.Printf("Could not find item with ID: %d\n", image.ItemId)
+			return apperrors.NewBadRequest("Could not find item with provided ID")
+		}
+
+		image.OwnerId = item.OwnerId
+
+		var count int64
+		if err := tx.Model(&models.Image{}).Where("item_id = ?", image.ItemId).Count(&count).Error; err != nil {
+			log.Printf("Could not count images of item %d: %v\n", image.ItemId, err)
+			return apperrors.NewInternal()
+		}
+		if count >= models.MaxImagesPerItem {
+			return apperrors.NewPayloadTooLargeWithMessage(fmt.Sprintf("An item can have at most %d images", models.MaxImagesPerItem))
+		}
 
-	
+		// New images go to the back; positions may have gaps left by deleted images
+		err := tx.Model(&models.Image{}).Where("item_id = ?", image.ItemId).
+			Select("COALESCE(MAX(position), -1) + 1").Scan(&image.Position).Error
+		if err != nil {
+			log.Printf("Could not find the position for a new image of item %d: %v\n", image.ItemId, err)
+			return apperrors.NewInternal()
+		}
+
+		if err := tx.Create(&image).Error; err != nil {
+			log.Print("Could not save image to database")
+			return apperrors.NewBadRequest("Could not save image to database")
+		}
+		return nil
+	})
+}
 
 
-	image.FilePath = folderName
-	image.FileName = fileName
-	image.ItemId = uint(itemId)
-	image.OwnerId = item.OwnerId
+func (r *imageRepository) GetImage(imageId int) (*models.Image, error) {
+	image := &models.Image{}
 
-	if err := r.DB.Create(&image).Error; err != nil {
-		log.Print("Could not save image to database")
-		return apperrors.NewBadRequest("Could not save image to database")
+	if err := r.DB.Where("id = ?", imageId).First(&image).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperrors.NewNotFound("image", strconv.Itoa(imageId))
+		}
+		log.Printf("Could not get image %d: %v\n", imageId, err)
+		return nil, apperrors.NewInternal()
 	}
-	return nil
+	return image, nil
 }
 
 
-func (r *imageRepository) ReadFirstImageById(id int) ([]byte, error) {
+func (r *imageRepository) GetFirstImage(id int) (*models.Image, error) {
 	item := &models.Item{}
 	image := &models.Image{}
 
 	if err := r.DB.Where("id = ?", id).First(&item).Error; err != nil {
-		log.Print("Item with ID %v does not exist\n", id)
+		log.Printf("Item with ID %v does not exist\n", id)
 		return nil, apperrors.NewBadRequest("Item with provided ID does not exist")
 	}
 
-	if err := r.DB.Where("item_id = ?", id).First(&image).Error; err != nil {
-		log.Print("Could not find image with item ID %v\n", id)
+	if err := r.DB.Where("item_id = ?", id).Order("position, id").First(&image).Error; err != nil {
+		log.Printf("Could not find image with item ID %v\n", id)
 		return nil, apperrors.NewBadRequest("Could not find image with provided item ID")
 	}
 
-	folderName := "item_" + strconv.Itoa(id)
-	fileName :=  image.FileName
-
-	return utils.ReadImage(folderName, fileName)
+	return image, nil
 }
 
 
-func (r *imageRepository) ReadAllImagesByItemId(id, limit, page int) ([]models.Image, error) { 
+func (r *imageRepository) ReadAllImagesByItemId(id int, request models.PageRequest) ([]models.Image, *models.Page, error) { 
 	item := &models.Item{}
 	var images []models.Image
 
 
-	if err := r.DB.Where("id = ?", id).Find(&item).Error; err != nil {
+	if err := r.DB.Where("id = ?", id).First(&item).Error; err != nil {
 
 		if errors.Is(err, gorm.ErrRecordNotFound){
-			log.Print("Error getting item with ID %d\n", id)
-			return images, apperrors.NewBadRequest("Could not find Item with provided ID")
+			log.Printf("Error getting item with ID %d\n", id)
+			return images, nil, apperrors.NewBadRequest("Could not find Item with provided ID")
 		}
 		log.Printf("Error getting item")
-		return images, apperrors.NewInternal()
+		return images, nil, apperrors.NewInternal()
 	}
 
-	if err := r.DB.Where("item_id = ?", id).Find(&images).Error; err != nil {
+	page, err := findOffsetPage(r.DB.Where("item_id = ?", id), "position, id", request, &images)
+	if err != nil {
 		log.Print("Could not find images for this item")
-		return images, apperrors.NewBadRequest("Could not find images for this item")
+		return images, nil, err
+	}
+
+	return images, page, nil
+}
+
+
+func (r *imageRepository) CountImages(itemId uint) (int64, error) {
+	var count int64
+
+	if err := r.DB.Model(&models.Image{}).Where("item_id = ?", itemId).Count(&count).Error; err != nil {
+		log.Printf("Could not count images of item %d: %v\n", itemId, err)
+		return 0, apperrors.NewInternal()
 	}
+	return count, nil
+}
+
+
+func (r *imageRepository) GetImagesByItemId(itemId uint) ([]models.Image, error) {
+	var images []models.Image
+
+	if err := r.DB.Where("item_id = ?", itemId).Order("position, id").Find(&images).Error; err != nil {
+		log.Printf("Could not get images of item %d: %v\n", itemId, err)
+		return nil, apperrors.NewInternal()
+	}
+	return images, nil
+}
 
+
+func (r *imageRepository) DeleteImage(imageId uint) error {
+	if err := r.DB.Unscoped().Delete(&models.Image{}, imageId).Error; err != nil {
+		log.Printf("Could not delete image %d: %v\n", imageId, err)
+		return apperrors.NewInternal()
+	}
+	return nil
+}
+
+
+func (r *imageRepository) CountImagesByFile(filePath, fileName string) (int64, error) {
+	var count int64
+
+	err := r.DB.Model(&models.Image{}).Where("file_path = ? AND file_name = ?", filePath, fileName).Count(&count).Error
+	if err != nil {
+		log.Printf("Could not count images stored as %s/%s: %v\n", filePath, fileName, err)
+		return 0, apperrors.NewInternal()
+	}
+	return count, nil
+}
+
+
+func (r *imageRepository) ReorderImages(itemId uint, imageIds []uint) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		var existing []uint
+		if err := tx.Model(&models.Image{}).Where("item_id = ?", itemId).Pluck("id", &existing).Error; err != nil {
+			log.Printf("Could not get images of item %d: %v\n", itemId, err)
+			return apperrors.NewInternal()
+		}
+
+		remaining := map[uint]bool{}
+		for _, id := range existing {
+			remaining[id] = true
+		}
+		for _, id := range imageIds {
+			if !remaining[id] {
+				return apperrors.NewBadRequest(fmt.Sprintf("Image %d is not an image of this item or is listed twice", id))
+			}
+			delete(remaining, id)
+		}
+		if len(remaining) > 0 {
+			return apperrors.NewBadRequest("Every image of the item must be listed")
+		}
+
+		for position, id := range imageIds {
+			if err := tx.Model(&models.Image{}).Where("id = ?", id).Update("position", position).Error; err != nil {
+				log.Printf("Could not move image %d of item %d: %v\n", id, itemId, err)
+				return apperrors.NewInternal()
+			}
+		}
+		return nil
+	})
+}
+
+
+func (r *imageRepository) MarkVariantsReady(imageId uint) error {
+	result := r.DB.Model(&models.Image{}).Where("id = ?", imageId).Update("variants_at", time.Now())
+	if result.Error != nil {
+		log.Printf("Could not mark variants of image %d as ready: %v\n", imageId, result.Error)
+		return apperrors.NewInternal()
+	}
+	if result.RowsAffected == 0 {
+		return apperrors.NewNotFound("image", strconv.Itoa(int(imageId)))
+	}
+	return nil
+}
+
+
+func (r *imageRepository) GetImagesWithoutVariants(afterId uint, limit int) ([]models.Image, error) {
+	var images []models.Image
+
+	if err := r.DB.Where("variants_at IS NULL AND id > ?", afterId).Order("id").Limit(limit).Find(&images).Error; err != nil {
+		log.Printf("Could not get images without variants: %v\n", err)
+		return nil, apperrors.NewInternal()
+	}
 	return images, nil
 }
@@ -4,13 +4,18 @@ import (
 	"swap/models"
 	"swap/apperrors"
 	
+	"encoding/json"
 	"errors"
+	"html"
 	"strconv"
+	"strings"
 	"fmt"
 	"log"
+	"math"
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type itemRepository struct {
@@ -25,20 +30,30 @@ func NewItemRepository(db *gorm.DB) models.IItemRepository {
 
 
 func (r *itemRepository) UpdateCategory(itemId int, categoryName string) error {
-	category := &models.Category{}
 	item,err := r.GetItemById(itemId)
 
 	if err != nil {
 		return apperrors.NewNotFound("ID", strconv.Itoa(itemId))
 	}
 
-	if err := r.DB.Where("name = ?", categoryName).First(&category).Error; err != nil {
-		return apperrors.NewBadRequest("Category does not exist")
+	category, err := findCategory(r.DB, categoryName)
+	if err != nil || category.Ban {
+		return apperrors.NewBadRequest("Invalid category: " + categoryName)
+	}
+
+	if err := category.AttributeSchema.Validate(item.Attributes); err != nil {
+		return apperrors.NewBadRequest(err.Error())
 	}
 
 	item.CategoryId = &category.ID
 	item.CategoryName = category.Name
-	if err := r.DB.Save(&item).Error; err != nil {
+	err = r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&item).Error; err != nil {
+			return err
+		}
+		return recordRevision(tx, item)
+	})
+	if err != nil {
 		return apperrors.NewBadRequest("Failed to update category")
 	}
 	return nil
@@ -50,7 +65,7 @@ func (r *itemRepository) GetItemById(id int) (*models.Item, error) {
 	itemId := strconv.Itoa(id)
 
 	if err := r.DB.Where("id = ?", itemId).Find(&item).Error; err != nil {
-		log.Printf("Error getting item with ID %d\n", itemId)
+		log.Printf("Error getting item with ID %s\n", itemId)
 
 		if errors.Is(err, gorm.ErrRecordNotFound){
 			return item, apperrors.NewNotFound("ID", itemId)
@@ -75,17 +90,229 @@ func (r * itemRepository) GetByUUID(uuid string) (*models.Item, error) {
 }
 
 
-func (r *itemRepository) GetItemsByCategory(category string, limit, page int) ([]models.Item, error) {
+// QueryItems returns one page of the items matching query together with
+// category and price facets. Without a sort key the page is keyset paged in
+// listing order; any other sort falls back to page numbers.
+func (r *itemRepository) QueryItems(query models.ItemQuery, request models.PageRequest) ([]models.Item, *models.ItemFacets, *models.Page, error) {
 	var items []models.Item
 
-	err := r.DB.Joins("JOIN categories ON categories.id = items.category_id").
-				Where("categories.name = ?", category).
-				Find(&items)
+	var categoryIds []uint
+	if query.Category != "" {
+		ids, err := r.categoryTree(query.Category)
+		if err != nil {
+			return items, nil, nil, err
+		}
+		categoryIds = ids
+	}
 
-	if err.Error != nil {
-		return items, apperrors.NewInternal()
+	matches := r.filterItems(query, categoryIds, "")
+
+	var page *models.Page
+	var err error
+	switch query.Sort {
+	case models.SortNewest:
+		page, err = findOffsetPage(matches, "items.created_at DESC, items.id DESC", request, &items)
+	case models.SortOldest:
+		page, err = findOffsetPage(matches, "items.created_at, items.id", request, &items)
+	case models.SortPriceAsc:
+		page, err = findOffsetPage(matches, "items.prize, items.id", request, &items)
+	case models.SortPriceDesc:
+		page, err = findOffsetPage(matches, "items.prize DESC, items.id", request, &items)
+	case models.SortDistance:
+		byDistance := clause.OrderBy{Expression: clause.Expr{
+			SQL:  distanceSQL + ", items.id",
+			Vars: distanceVars(*query.Near),
+		}}
+		page, err = findOffsetPage(matches, byDistance, request, &items)
+	default:
+		page, err = findPage(matches, "items.id", request, &items)
 	}
-	return items, nil
+	if err != nil {
+		return items, nil, nil, err
+	}
+
+	facets, err := r.itemFacets(query, categoryIds)
+	if err != nil {
+		return items, nil, nil, err
+	}
+	return items, facets, page, nil
+}
+
+
+// filterItems builds the query for items matching every filter except the one
+// named by skip ("category" or "price"), which lets a facet ignore its own filter.
+// Items in banned categories are never returned.
+func (r *itemRepository) filterItems(query models.ItemQuery, categoryIds []uint, skip string) *gorm.DB {
+	db := r.DB.Model(&models.Item{}).
+		Joins("LEFT JOIN categories ON categories.id = items.category_id").
+		Where("categories.ban IS NOT TRUE")
+
+	if categoryIds != nil && skip != "category" {
+		db = db.Where("items.category_id IN ?", categoryIds)
+	}
+	if skip != "price" {
+		if query.MinPrize != nil {
+			db = db.Where("items.prize >= ?", *query.MinPrize)
+		}
+		if query.MaxPrize != nil {
+			db = db.Where("items.prize <= ?", *query.MaxPrize)
+		}
+	}
+	if query.ListedAfter != nil {
+		db = db.Where("items.created_at >= ?", *query.ListedAfter)
+	}
+	if query.ListedBefore != nil {
+		db = db.Where("items.created_at < ?", *query.ListedBefore)
+	}
+	if query.Sold != nil {
+		db = db.Where("items.sold = ?", *query.Sold)
+	}
+	if query.Giveaway != nil {
+		db = db.Where("items.giveaway = ?", *query.Giveaway)
+	}
+	if query.Sold == nil || !*query.Sold {
+		db = db.Where("items.expired = ?", false)
+	}
+	if query.Location != "" {
+		db = db.Joins("JOIN users ON users.id = items.owner_id").
+			Where("users.location ILIKE ?", "%"+query.Location+"%")
+	}
+	if query.Near != nil {
+		db = db.Where("items.latitude IS NOT NULL AND items.longitude IS NOT NULL")
+		if query.RadiusKm > 0 {
+			db = withinRadius(db, *query.Near, query.RadiusKm)
+		}
+	}
+	return applyAttributeFilters(db, "items.attributes", query.Attributes)
+}
+
+
+// distanceSQL is the haversine distance in km from the point given by
+// distanceVars to an item
+const distanceSQL = `(2 * ? * asin(least(1, sqrt(
+	power(sin(radians(items.latitude - ?) / 2), 2) +
+	cos(radians(?)) * cos(radians(items.latitude)) * power(sin(radians(items.longitude - ?) / 2), 2)))))`
+
+
+func distanceVars(from models.Coordinates) []interface{} {
+	return []interface{}{models.EarthRadiusKm, from.Latitude, from.Latitude, from.Longitude}
+}
+
+
+// withinRadius keeps items at most radiusKm from center. A bounding box on the
+// indexed latitude/longitude columns narrows the rows before the exact distance is computed.
+func withinRadius(db *gorm.DB, center models.Coordinates, radiusKm float64) *gorm.DB {
+	latDelta := radiusKm / models.EarthRadiusKm * 180 / math.Pi
+	db = db.Where("items.latitude BETWEEN ? AND ?", center.Latitude-latDelta, center.Latitude+latDelta)
+
+	// Longitude degrees shrink towards the poles; near them, or across the
+	// antimeridian, skip the longitude bound rather than get it wrong
+	cosLat := math.Cos(center.Latitude * math.Pi / 180)
+	if cosLat > 0.01 {
+		lngDelta := latDelta / cosLat
+		if center.Longitude-lngDelta >= -180 && center.Longitude+lngDelta <= 180 {
+			db = db.Where("items.longitude BETWEEN ? AND ?", center.Longitude-lngDelta, center.Longitude+lngDelta)
+		}
+	}
+
+	return db.Where(distanceSQL+" <= ?", append(distanceVars(center), radiusKm)...)
+}
+
+
+func (r *itemRepository) itemFacets(query models.ItemQuery, categoryIds []uint) (*models.ItemFacets, error) {
+	facets := &models.ItemFacets{
+		Categories: []models.CategoryFacet{},
+		Prices:     []models.PriceFacet{},
+	}
+
+	err := r.filterItems(query, categoryIds, "category").
+		Select("items.category_name AS name, count(*) AS count").
+		Group("items.category_name").
+		Order("count DESC, name").
+		Scan(&facets.Categories).Error
+	if err != nil {
+		log.Printf("Could not count category facets: %v\n", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	bounds := make([]string, len(models.PriceBucketBounds))
+	for i, bound := range models.PriceBucketBounds {
+		bounds[i] = strconv.FormatFloat(bound, 'f', -1, 64)
+	}
+
+	var buckets []struct {
+		Bucket  int
+		Count   int64
+	}
+	err = r.filterItems(query, categoryIds, "price").
+		Select("width_bucket(items.prize, ARRAY[" + strings.Join(bounds, ",") + "]::numeric[]) AS bucket, count(*) AS count").
+		Group("bucket").
+		Scan(&buckets).Error
+	if err != nil {
+		log.Printf("Could not count price facets: %v\n", err)
+		return nil, apperrors.NewInternal()
+	}
+
+	counts := map[int]int64{}
+	for _, bucket := range buckets {
+		counts[bucket.Bucket] = bucket.Count
+	}
+
+	min := 0.0
+	for i := 0; i <= len(models.PriceBucketBounds); i++ {
+		facet := models.PriceFacet{Min: min, Count: counts[i]}
+		if i < len(models.PriceBucketBounds) {
+			max := models.PriceBucketBounds[i]
+			facet.Max = &max
+			min = max
+		}
+		facets.Prices = append(facets.Prices, facet)
+	}
+	return facets, nil
+}
+
+
+// applyAttributeFilters narrows query to items whose attributes match filters.
+// A plain value must match exactly while {"min": x, "max": y} selects a numeric range.
+func applyAttributeFilters(query *gorm.DB, column string, filters models.Attributes) *gorm.DB {
+	for name, value := range filters {
+		bounds, isRange := value.(map[string]interface{})
+		if !isRange {
+			match, _ := json.Marshal(map[string]interface{}{name: value})
+			query = query.Where(column+" @> ?", string(match))
+			continue
+		}
+
+		// Items whose value for name is not a number, e.g. from a category with
+		// another schema, are left out rather than failing the cast; CASE keeps
+		// Postgres from casting before the type is checked
+		number := "CASE WHEN jsonb_typeof(" + column + " -> ?) = 'number' THEN (" + column + " ->> ?)::numeric END"
+		if min, ok := bounds["min"].(float64); ok {
+			query = query.Where(number+" >= ?", name, name, min)
+		}
+		if max, ok := bounds["max"].(float64); ok {
+			query = query.Where(number+" <= ?", name, name, max)
+		}
+	}
+	return query
+}
+
+
+// categoryTree resolves a category name or slug to the IDs of it and all its subcategories
+func (r *itemRepository) categoryTree(category string) ([]uint, error) {
+	found, err := findCategory(r.DB, category)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperrors.NewNotFound("category", category)
+		}
+		return nil, apperrors.NewInternal()
+	}
+
+	categoryIds, err := descendantCategoryIds(r.DB, found.ID)
+	if err != nil {
+		return nil, apperrors.NewInternal()
+	}
+	return categoryIds, nil
 }
 
 
@@ -96,7 +323,7 @@ func (r *itemRepository) RegisterItem(item *models.Item) (*models.Item, error) {
 		if err := tx.Where("name = ? AND ban = ?", item.CategoryName, false).First(&category).Error; err != nil {
 			if errors.Is(err, gorm.ErrRecordNotFound){
 				log.Print("Invalid category")
-				return fmt.Errorf("Invalid category: %v", item.CategoryName)
+				return apperrors.NewBadRequest(fmt.Sprintf("Invalid category: %v", item.CategoryName))
 			}
 			return err
 		}
@@ -112,24 +339,21 @@ func (r *itemRepository) RegisterItem(item *models.Item) (*models.Item, error) {
 
 	if err != nil {
 		log.Print("Error")
-		return nil, apperrors.NewInternal()
+		return nil, apperrors.GetAppError(err, apperrors.ServerError)
 	}
 
 	return item, nil
 }
 
 
-func (r *itemRepository) GetUnsoldItemsByCategory(category string, limit, page int) ([]models.Item, error) {
-	var items []models.Item
-
-	err := r.DB.Joins("JOIN categories ON categories.id = items.category_id").
-				Where("categories.name = ? AND categories.ban = ? AND items.sold = ?", category, false, false).
-				Find(&items)
-
-	if err.Error != nil {
-		return items, apperrors.NewInternal()
+// RegisterItems inserts items whose category has already been resolved, all
+// or nothing, in batches of models.ImportBatchSize
+func (r *itemRepository) RegisterItems(items []*models.Item) error {
+	if err := r.DB.CreateInBatches(items, models.ImportBatchSize).Error; err != nil {
+		log.Printf("Could not register %d items: %v\n", len(items), err)
+		return apperrors.NewInternal()
 	}
-	return items, nil
+	return nil
 }
 
 
@@ -155,17 +379,133 @@ func (r *itemRepository) UpdateItem(item models.Item) error {
 	if item.Prize >= 0.00 {
 		updatedDetails["Prize"] = item.Prize
 	}
-	if  !item.Sold {
-		updatedDetails["Sold"] = item.Sold
+	if item.Attributes != nil {
+		updatedDetails["Attributes"] = item.Attributes
+	}
+	if item.Coordinates() != nil {
+		updatedDetails["Latitude"] = item.Latitude
+		updatedDetails["Longitude"] = item.Longitude
 	}
 
-	if err := r.DB.Model(&foundItem).Updates(updatedDetails).Error; err != nil {
+	err = r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&foundItem).Updates(updatedDetails).Error; err != nil {
+			return err
+		}
+		return recordRevision(tx, foundItem)
+	})
+	if err != nil {
 		return apperrors.NewInternal()
 	}
 	return nil
 }
 
 
+// RelistItem restarts the listing period of an item and, when a new price is
+// given, changes it as a new revision
+func (r *itemRepository) RelistItem(itemId uint, prize *float64, expiresAt *time.Time) error {
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		item := &models.Item{}
+		if err := tx.Where("id = ?", itemId).First(item).Error; err != nil {
+			return err
+		}
+
+		updates := map[string]interface{}{"expired": false, "expires_at": expiresAt, "reminded_at": nil}
+		if prize != nil {
+			updates["prize"] = *prize
+		}
+		if err := tx.Model(item).Updates(updates).Error; err != nil {
+			return err
+		}
+		return recordRevision(tx, item)
+	})
+	if err != nil {
+		log.Printf("Could not relist item %d: %v\n", itemId, err)
+		return apperrors.NewInternal()
+	}
+	return nil
+}
+
+
+// GetItemsExpiringBefore returns listed items running out before the given
+// time whose owners have not been reminded yet
+func (r *itemRepository) GetItemsExpiringBefore(before time.Time) ([]models.Item, error) {
+	var items []models.Item
+
+	err := r.DB.Where("expires_at < ? AND reminded_at IS NULL AND sold = ? AND expired = ?", before, false, false).
+		Find(&items).Error
+	if err != nil {
+		log.Printf("Could not find expiring items: %v\n", err)
+		return items, apperrors.NewInternal()
+	}
+	return items, nil
+}
+
+
+func (r *itemRepository) MarkReminded(itemIds []uint) error {
+	if err := r.DB.Model(&models.Item{}).Where("id IN ?", itemIds).Update("reminded_at", time.Now()).Error; err != nil {
+		return apperrors.NewInternal()
+	}
+	return nil
+}
+
+
+// ExpireItems takes every listing past its expiry off the market and returns them
+func (r *itemRepository) ExpireItems(now time.Time) ([]models.Item, error) {
+	var items []models.Item
+
+	err := r.DB.Model(&items).Clauses(clause.Returning{}).
+		Where("expires_at <= ? AND sold = ? AND expired = ?", now, false, false).
+		Update("expired", true).Error
+	if err != nil {
+		log.Printf("Could not expire items: %v\n", err)
+		return items, apperrors.NewInternal()
+	}
+	return items, nil
+}
+
+
+// recordRevision stores a new revision of item, already holding its updated
+// values, unless none of the details a revision covers have changed
+func recordRevision(tx *gorm.DB, item *models.Item) error {
+	revision := models.RevisionOf(item)
+
+	// Locking the item row makes concurrent edits number their revisions one
+	// after the other instead of both claiming the same number
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").Where("id = ?", item.ID).First(&models.Item{}).Error
+	if err != nil {
+		return err
+	}
+
+	var latest models.ItemRevision
+	err = tx.Where("item_id = ?", item.ID).Order("revision DESC").Limit(1).Find(&latest).Error
+	if err != nil {
+		return err
+	}
+
+	if latest.ID != 0 && len(revision.Diff(latest)) == 0 {
+		return nil
+	}
+
+	revision.Revision = latest.Revision + 1
+	if err := tx.Create(&revision).Error; err != nil {
+		return err
+	}
+
+	item.Revision = revision.Revision
+	return tx.Model(&models.Item{}).Where("id = ?", item.ID).Update("revision", revision.Revision).Error
+}
+
+
+func (r *itemRepository) GetItemRevisions(itemId uint) ([]models.ItemRevision, error) {
+	var revisions []models.ItemRevision
+
+	if err := r.DB.Where("item_id = ?", itemId).Order("revision").Find(&revisions).Error; err != nil {
+		return revisions, apperrors.NewInternal()
+	}
+	return revisions, nil
+}
+
+
 func (r *itemRepository) DeleteItem(id int) error {
 	if err := r.DB.Where("id = ?", id).Delete(&models.Item{}).Error; err != nil {
 		return err
@@ -174,14 +514,15 @@ func (r *itemRepository) DeleteItem(id int) error {
 }
 
 
-func (r *itemRepository) GetItemsByOwnerId(ownerId uint, limit, page int) ([]models.Item, error) {
+func (r *itemRepository) GetItemsByOwnerId(ownerId uint, request models.PageRequest) ([]models.Item, *models.Page, error) {
 	var items []models.Item
 	
-	err := r.DB.Select("name", "description", "category_name", "prize", "sold","ID").Where("owner_id = ?", ownerId).Find(&items)
-	if err.Error != nil {
-		return items, apperrors.NewInternal()
+	query := r.DB.Select("name", "description", "category_name", "prize", "sold", "attributes", "ID").Where("owner_id = ?", ownerId)
+	page, err := findPage(query, "id", request, &items)
+	if err != nil {
+		return items, nil, err
 	}
-	return items, nil
+	return items, page, nil
 }
 
 
@@ -210,6 +551,14 @@ func (r *itemRepository) BuyItem(userID, id int, amount float64) (string, error)
 	}
 
 
+	if item.Giveaway {
+		return "", apperrors.NewBadRequest("Give-away items are claimed, not bought")
+	}
+
+	if item.Expired {
+		return "", apperrors.NewBadRequest("Listing has expired")
+	}
+
 	if item.Sold == true {
 		log.Print("Item have already been sold\n")
 		return "", apperrors.NewBadRequest("Item have aleady been sold")
@@ -227,6 +576,7 @@ func (r *itemRepository) BuyItem(userID, id int, amount float64) (string, error)
 		PhoneNumber: owner.PhoneNumber,
 		OwnerId: user.ID,
 		ItemId: item.ID,
+		ItemRevision: item.Revision,
 		ItemName: item.Name,
 		Bought:  true,
 		Swapped: false,
@@ -250,3 +600,63 @@ func (r *itemRepository) BuyItem(userID, id int, amount float64) (string, error)
 	return result, nil
 }
 
+
+// SearchItems runs a free-text search over unsold items outside banned
+// categories. websearch_to_tsquery gives users "quoted phrases", OR and
+// -exclusions, while the trigram match on the name keeps results coming for
+// misspelt queries that match no lexeme.
+// Results are ordered by relevance, so paging is by page number rather than cursor.
+func (r *itemRepository) SearchItems(query string, request models.PageRequest) ([]models.ItemSearchHit, *models.Page, error) {
+	var hits []models.ItemSearchHit
+	page := &models.Page{Limit: request.Limit}
+
+	matches := r.DB.Model(&models.Item{}).
+		Joins("LEFT JOIN categories ON categories.id = items.category_id").
+		Where("categories.ban IS NOT TRUE").
+		Where("items.sold = ? AND items.expired = ?", false, false).
+		Where("items.search_vector @@ websearch_to_tsquery('english', ?) OR ? <% items.name", query, query)
+
+	if err := matches.Session(&gorm.Session{}).Count(&page.Total).Error; err != nil {
+		log.Printf("Could not count search results: %v\n", err)
+		return hits, nil, apperrors.NewInternal()
+	}
+
+	selectors := fmt.Sprintf(`StartSel="%s", StopSel="%s"`, highlightStart, highlightStop)
+
+	err := matches.Select(`items.*,
+			ts_rank_cd(items.search_vector, websearch_to_tsquery('english', ?)) + word_similarity(?, items.name) AS rank,
+			ts_headline('english', items.name, websearch_to_tsquery('english', ?), ?) AS headline,
+			ts_headline('english', items.description, websearch_to_tsquery('english', ?), ?) AS snippet`,
+			query, query, query, selectors+", HighlightAll=true",
+			query, selectors+", MaxFragments=2, MaxWords=20, MinWords=5").
+		Order("rank DESC, items.id").
+		Offset((request.Page - 1) * request.Limit).
+		Limit(request.Limit).
+		Scan(&hits).Error
+
+	if err != nil {
+		log.Printf("Could not search items: %v\n", err)
+		return hits, nil, apperrors.NewInternal()
+	}
+
+	for i := range hits {
+		hits[i].Headline = markHighlights(hits[i].Headline)
+		hits[i].Snippet = markHighlights(hits[i].Snippet)
+	}
+	return hits, page, nil
+}
+
+
+// Postgres wraps matches in these private-use characters instead of <mark>
+// tags, so the listing's own text can be escaped before the marks go in
+const (
+	highlightStart = "\uE000"
+	highlightStop  = "\uE001"
+)
+
+
+// markHighlights turns a ts_headline result into safe HTML
+func markHighlights(text string) string {
+	text = html.EscapeString(text)
+	return strings.NewReplacer(highlightStart, "<mark>", highlightStop, "</mark>").Replace(text)
+}
@@ -0,0 +1,148 @@
+package repository
+
+import (
+	"errors"
+	"log"
+	"strconv"
+	"time"
+
+	"swap/apperrors"
+	"swap/models"
+
+	"gorm.io/gorm"
+)
+
+
+type listingFlagRepository struct {
+	DB *gorm.DB
+}
+
+func NewListingFlagRepository(db *gorm.DB) models.IListingFlagRepository {
+	return &listingFlagRepository{
+		DB: db,
+	}
+}
+
+
+// GetCandidateItems narrows other users' listings down with the trigram index
+// on names; the owner's own listings are all compared
+func (r *listingFlagRepository) GetCandidateItems(ownerId uint, name string, since time.Time) ([]models.Item, error) {
+	var items []models.Item
+
+	err := r.DB.Select("id", "owner_id", "name", "description").
+		Where("sold = ? AND (owner_id = ? OR (created_at > ? AND name % ?))", false, ownerId, since, name).
+		Order("id DESC").
+		Limit(models.DuplicateCandidateLimit).
+		Find(&items).Error
+	if err != nil {
+		log.Printf("Could not find duplicate candidates for owner %d: %v\n", ownerId, err)
+		return items, apperrors.NewInternal()
+	}
+	return items, nil
+}
+
+
+func (r *listingFlagRepository) GetCandidateImageHashes(ownerId, itemId uint, since time.Time) ([]models.ImageHash, error) {
+	var hashes []models.ImageHash
+
+	err := r.DB.Table("images").
+		Select("images.item_id, items.owner_id, images.hash").
+		Joins("JOIN items ON items.id = images.item_id AND items.deleted_at IS NULL").
+		Where("images.deleted_at IS NULL AND images.hash IS NOT NULL AND images.item_id <> ?", itemId).
+		Where("items.owner_id = ? OR images.created_at > ?", ownerId, since).
+		Order("images.id DESC").
+		Limit(models.DuplicateCandidateLimit).
+		Scan(&hashes).Error
+	if err != nil {
+		log.Printf("Could not find image hashes for owner %d: %v\n", ownerId, err)
+		return hashes, apperrors.NewInternal()
+	}
+	return hashes, nil
+}
+
+
+func (r *listingFlagRepository) CreateFlags(flags []models.ListingFlag) error {
+	if err := r.DB.Omit("Item").Create(&flags).Error; err != nil {
+		log.Printf("Could not save listing flags: %v\n", err)
+		return apperrors.NewInternal()
+	}
+	return nil
+}
+
+
+func (r *listingFlagRepository) GetFlags(status string, request models.PageRequest) ([]models.ListingFlag, *models.Page, error) {
+	var flags []models.ListingFlag
+
+	page, err := findPage(r.DB.Where("status = ?", status), "id", request, &flags)
+	if err != nil {
+		return flags, nil, err
+	}
+
+	// Items are loaded separately because paging counts the flags first.
+	// Removed listings are still shown on the flags that removed them.
+	itemIds := make([]uint, len(flags))
+	for i, flag := range flags {
+		itemIds[i] = flag.ItemId
+	}
+
+	var items []models.Item
+	if err := r.DB.Unscoped().Where("id IN ?", itemIds).Find(&items).Error; err != nil {
+		log.Printf("Could not load flagged items: %v\n", err)
+		return flags, nil, apperrors.NewInternal()
+	}
+
+	byId := make(map[uint]models.Item, len(items))
+	for _, item := range items {
+		byId[item.ID] = item
+	}
+	for i := range flags {
+		flags[i].Item = byId[flags[i].ItemId]
+	}
+	return flags, page, nil
+}
+
+
+// ReviewFlag closes a flag and, when the listing is removed, deletes the
+// item together with any other open flags on it
+func (r *listingFlagRepository) ReviewFlag(flagId, reviewerId uint, status string) (*models.ListingFlag, error) {
+	flag := &models.ListingFlag{}
+
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("id = ?", flagId).First(flag).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return apperrors.NewNotFound("flag", strconv.Itoa(int(flagId)))
+			}
+			return err
+		}
+
+		if flag.Status != models.FlagOpen {
+			return apperrors.NewBadRequest("Flag has already been reviewed")
+		}
+
+		now := time.Now()
+		review := map[string]interface{}{"status": status, "reviewed_by": reviewerId, "reviewed_at": now}
+
+		closing := tx.Model(&models.ListingFlag{}).Where("id = ?", flag.ID)
+		if status == models.FlagRemoved {
+			closing = tx.Model(&models.ListingFlag{}).Where("item_id = ? AND status = ?", flag.ItemId, models.FlagOpen)
+		}
+		if err := closing.Updates(review).Error; err != nil {
+			return err
+		}
+
+		flag.Status = status
+		flag.ReviewedBy = &reviewerId
+		flag.ReviewedAt = &now
+
+		if status == models.FlagRemoved {
+			return tx.Delete(&models.Item{}, flag.ItemId).Error
+		}
+		return nil
+	})
+
+	if err != nil {
+		log.Printf("Could not review flag %d: %v\n", flagId, err)
+		return nil, apperrors.GetAppError(err, apperrors.ServerError)
+	}
+	return flag, nil
+}
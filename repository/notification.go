@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"log"
+	"time"
+
+	"swap/apperrors"
+	"swap/models"
+
+	"gorm.io/gorm"
+)
+
+
+type notificationRepository struct {
+	DB *gorm.DB
+}
+
+
+func NewNotificationRepository(db *gorm.DB) models.INotificationRepository {
+	return &notificationRepository{
+		DB: db,
+	}
+}
+
+
+func (r *notificationRepository) CreateNotification(notification *models.Notification) error {
+	if err := r.DB.Create(notification).Error; err != nil {
+		log.Printf("Could not create notification for user %d: %v\n", notification.UserId, err)
+		return apperrors.NewInternal()
+	}
+	return nil
+}
+
+
+func (r *notificationRepository) GetNotifications(userId uint, unreadOnly bool, request models.PageRequest) ([]models.Notification, *models.Page, error) {
+	var notifications []models.Notification
+
+	query := r.DB.Where("user_id = ?", userId)
+	if unreadOnly {
+		query = query.Where("read_at IS NULL")
+	}
+
+	page, err := findPage(query, "id", request, &notifications)
+	if err != nil {
+		return notifications, nil, err
+	}
+	return notifications, page, nil
+}
+
+
+// MarkRead marks the given notifications of the user as read, or all of them when none are given
+func (r *notificationRepository) MarkRead(userId uint, notificationIds []uint) error {
+	query := r.DB.Model(&models.Notification{}).Where("user_id = ? AND read_at IS NULL", userId)
+	if len(notificationIds) > 0 {
+		query = query.Where("id IN ?", notificationIds)
+	}
+
+	if err := query.Update("read_at", time.Now()).Error; err != nil {
+		return apperrors.NewInternal()
+	}
+	return nil
+}
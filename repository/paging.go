@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"reflect"
+
+	"swap/apperrors"
+	"swap/models"
+
+	"gorm.io/gorm"
+)
+
+
+// findPage loads one page of query into dest, a pointer to a slice of structs
+// with an ID field, using keyset pagination on column (e.g. "id" or "items.id").
+// One extra row is fetched to find out whether another page follows.
+func findPage(query *gorm.DB, column string, request models.PageRequest, dest interface{}) (*models.Page, error) {
+	cursor, err := models.DecodeCursor(request.Cursor)
+	if err != nil {
+		return nil, apperrors.NewBadRequest("Invalid page cursor")
+	}
+
+	query = query.Session(&gorm.Session{})
+	page := &models.Page{Limit: request.Limit}
+
+	if err := query.Model(dest).Count(&page.Total).Error; err != nil {
+		return nil, apperrors.NewInternal()
+	}
+
+	paged := query
+	switch {
+	case cursor.Before > 0:
+		paged = paged.Where(column+" < ?", cursor.Before).Order(column + " DESC")
+	case cursor.After > 0:
+		paged = paged.Where(column+" > ?", cursor.After).Order(column)
+	default:
+		paged = paged.Order(column).Offset((request.Page - 1) * request.Limit)
+	}
+
+	if err := paged.Limit(request.Limit + 1).Find(dest).Error; err != nil {
+		return nil, apperrors.NewInternal()
+	}
+
+	rows := reflect.ValueOf(dest).Elem()
+	hasMore := rows.Len() > request.Limit
+	if hasMore {
+		rows.Set(rows.Slice(0, request.Limit))
+	}
+
+	if rows.Len() == 0 {
+		return page, nil
+	}
+
+	if cursor.Before > 0 {
+		swap := reflect.Swapper(rows.Interface())
+		for i, j := 0, rows.Len()-1; i < j; i, j = i+1, j-1 {
+			swap(i, j)
+		}
+	}
+
+	first := uint(rows.Index(0).FieldByName("ID").Uint())
+	last := uint(rows.Index(rows.Len() - 1).FieldByName("ID").Uint())
+
+	// Walking backwards there is always a next page: the one we came from
+	hasNext := hasMore || cursor.Before > 0
+	hasPrev := cursor.After > 0 || request.Page > 1 || (cursor.Before > 0 && hasMore)
+
+	if hasNext {
+		page.NextCursor = models.Cursor{After: last}.Encode()
+	}
+	if hasPrev {
+		page.PrevCursor = models.Cursor{Before: first}.Encode()
+	}
+	return page, nil
+}
+
+
+// findOffsetPage loads one page of query into dest in the given order (a string
+// or clause expression), for lists whose order is not by ID and so cannot use
+// cursors. Pages are chosen by number.
+func findOffsetPage(query *gorm.DB, order interface{}, request models.PageRequest, dest interface{}) (*models.Page, error) {
+	query = query.Session(&gorm.Session{})
+	page := &models.Page{Limit: request.Limit}
+
+	if err := query.Model(dest).Count(&page.Total).Error; err != nil {
+		return nil, apperrors.NewInternal()
+	}
+
+	err := query.Order(order).
+		Offset((request.Page - 1) * request.Limit).
+		Limit(request.Limit).
+		Find(dest).Error
+	if err != nil {
+		return nil, apperrors.NewInternal()
+	}
+	return page, nil
+}
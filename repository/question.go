@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"errors"
+	"log"
+	"strconv"
+	"time"
+
+	"swap/apperrors"
+	"swap/models"
+
+	"gorm.io/gorm"
+)
+
+
+type questionRepository struct {
+	DB *gorm.DB
+}
+
+func NewQuestionRepository(db *gorm.DB) models.IQuestionRepository {
+	return &questionRepository{
+		DB: db,
+	}
+}
+
+
+func (r *questionRepository) CreateQuestion(question *models.Question) error {
+	if err := r.DB.Create(question).Error; err != nil {
+		log.Printf("Could not create question on item %d: %v\n", question.ItemId, err)
+		return apperrors.NewInternal()
+	}
+	return nil
+}
+
+
+func (r *questionRepository) GetQuestion(itemId, questionId uint) (*models.Question, error) {
+	question := &models.Question{}
+
+	if err := r.DB.Where("id = ? AND item_id = ?", questionId, itemId).First(question).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperrors.NewNotFound("question", strconv.Itoa(int(questionId)))
+		}
+		return nil, apperrors.NewInternal()
+	}
+	return question, nil
+}
+
+
+func (r *questionRepository) GetQuestions(itemId uint, answeredOnly bool, request models.PageRequest) ([]models.Question, *models.Page, error) {
+	var questions []models.Question
+
+	query := r.DB.Where("item_id = ?", itemId)
+	if answeredOnly {
+		query = query.Where("answered_at IS NOT NULL")
+	}
+
+	page, err := findPage(query, "id", request, &questions)
+	if err != nil {
+		return questions, nil, err
+	}
+	return questions, page, nil
+}
+
+
+// AnswerQuestion sets or replaces the answer to a question
+func (r *questionRepository) AnswerQuestion(question *models.Question, answer string) error {
+	now := time.Now()
+
+	err := r.DB.Model(question).Updates(map[string]interface{}{"answer": answer, "answered_at": now}).Error
+	if err != nil {
+		log.Printf("Could not answer question %d: %v\n", question.ID, err)
+		return apperrors.NewInternal()
+	}
+
+	question.Answer = answer
+	question.AnsweredAt = &now
+	return nil
+}
+
+
+func (r *questionRepository) DeleteQuestion(question *models.Question) error {
+	if err := r.DB.Delete(question).Error; err != nil {
+		log.Printf("Could not delete question %d: %v\n", question.ID, err)
+		return apperrors.NewInternal()
+	}
+	return nil
+}
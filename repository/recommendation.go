@@ -0,0 +1,155 @@
+package repository
+
+import (
+	"log"
+	"time"
+
+	"swap/apperrors"
+	"swap/models"
+
+	"gorm.io/gorm"
+)
+
+
+type recommendationRepository struct {
+	DB *gorm.DB
+}
+
+
+func NewRecommendationRepository(db *gorm.DB) models.IRecommendationRepository {
+	return &recommendationRepository{
+		DB: db,
+	}
+}
+
+
+// interactionsSQL yields one row per user and item the user showed interest
+// in, weighted by the strongest interaction
+const interactionsSQL = `interactions AS (
+	SELECT user_id, item_id, max(weight) AS weight FROM (
+		SELECT substr(viewer_key, 6)::bigint AS user_id, item_id, @view_weight::float AS weight
+			FROM item_views WHERE viewer_key LIKE 'user:%'
+		UNION ALL
+		SELECT user_id, item_id, @watch_weight FROM watches
+		UNION ALL
+		SELECT initiator_id, item2_id, @swap_weight FROM swap_requests WHERE deleted_at IS NULL
+		UNION ALL
+		SELECT owner_id, item_id, @purchase_weight FROM transactions WHERE deleted_at IS NULL AND bought
+	) signals
+	GROUP BY user_id, item_id
+)`
+
+
+func interactionWeights() map[string]interface{} {
+	return map[string]interface{}{
+		"view_weight":     models.ViewWeight,
+		"watch_weight":    models.WatchWeight,
+		"swap_weight":     models.SwapRequestWeight,
+		"purchase_weight": models.PurchaseWeight,
+	}
+}
+
+
+// ComputeSimilarities rebuilds the item-item similarity table from scratch.
+// Two items are similar when the same users are interested in both; the score
+// is the cosine similarity of their weighted interaction vectors.
+func (r *recommendationRepository) ComputeSimilarities() error {
+	params := interactionWeights()
+	params["kept"] = models.SimilarItemsKept
+	params["now"] = time.Now()
+
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM item_similarities").Error; err != nil {
+			return err
+		}
+
+		return tx.Exec(`WITH `+interactionsSQL+`,
+			norms AS (
+				SELECT item_id, sqrt(sum(weight * weight)) AS norm FROM interactions GROUP BY item_id
+			),
+			pairs AS (
+				SELECT a.item_id, b.item_id AS similar_item_id, sum(a.weight * b.weight) AS dot
+				FROM interactions a JOIN interactions b ON a.user_id = b.user_id AND a.item_id <> b.item_id
+				GROUP BY a.item_id, b.item_id
+			),
+			ranked AS (
+				SELECT pairs.item_id, pairs.similar_item_id, pairs.dot / (na.norm * nb.norm) AS score,
+					row_number() OVER (PARTITION BY pairs.item_id ORDER BY pairs.dot / (na.norm * nb.norm) DESC) AS rank
+				FROM pairs
+				JOIN norms na ON na.item_id = pairs.item_id
+				JOIN norms nb ON nb.item_id = pairs.similar_item_id
+			)
+			INSERT INTO item_similarities (item_id, similar_item_id, score, computed_at)
+			SELECT item_id, similar_item_id, score, @now FROM ranked WHERE rank <= @kept`, params).Error
+	})
+
+	if err != nil {
+		log.Printf("Could not compute item similarities: %v\n", err)
+		return apperrors.NewInternal()
+	}
+	return nil
+}
+
+
+// GetRecommendations scores unsold items by their similarity to everything the
+// user has interacted with, leaving out the user's own items and ones they already know
+func (r *recommendationRepository) GetRecommendations(userId uint, limit int) ([]models.Recommendation, error) {
+	var recommendations []models.Recommendation
+
+	params := interactionWeights()
+	params["user"] = userId
+	params["limit"] = limit
+
+	err := r.DB.Raw(`WITH `+interactionsSQL+`,
+		mine AS (
+			SELECT item_id, weight FROM interactions WHERE user_id = @user
+		),
+		scores AS (
+			SELECT s.similar_item_id AS item_id, sum(s.score * mine.weight) AS score
+			FROM item_similarities s JOIN mine ON mine.item_id = s.item_id
+			WHERE s.similar_item_id NOT IN (SELECT item_id FROM mine)
+			GROUP BY s.similar_item_id
+		)
+		SELECT items.*, scores.score FROM scores
+		JOIN items ON items.id = scores.item_id
+		WHERE items.deleted_at IS NULL AND items.sold = false AND items.expired = false AND items.owner_id <> @user
+		ORDER BY scores.score DESC, items.id
+		LIMIT @limit`, params).Scan(&recommendations).Error
+
+	if err != nil {
+		log.Printf("Could not recommend items to user %d: %v\n", userId, err)
+		return recommendations, apperrors.NewInternal()
+	}
+	return recommendations, nil
+}
+
+
+// GetPopularItems ranks unsold items by recent views, watches and swap
+// requests, for users without enough history to base recommendations on
+func (r *recommendationRepository) GetPopularItems(userId uint, limit int) ([]models.Recommendation, error) {
+	var recommendations []models.Recommendation
+
+	since := models.Day(time.Now()).AddDate(0, 0, -models.DefaultAnalyticsDays)
+
+	err := r.DB.Raw(`SELECT items.*, popularity.score FROM (
+			SELECT item_id, sum(views * @view_weight + watches * @watch_weight + swap_requests * @swap_weight) AS score
+			FROM item_daily_stats WHERE day >= @since GROUP BY item_id
+		) popularity
+		JOIN items ON items.id = popularity.item_id
+		WHERE items.deleted_at IS NULL AND items.sold = false AND items.expired = false AND items.owner_id <> @user
+		ORDER BY popularity.score DESC, items.id DESC
+		LIMIT @limit`, map[string]interface{}{
+			"view_weight":  models.ViewWeight,
+			"watch_weight": models.WatchWeight,
+			"swap_weight":  models.SwapRequestWeight,
+			"since":        since,
+			"user":         userId,
+			"limit":        limit,
+		}).Scan(&recommendations).Error
+
+	if err != nil {
+		log.Printf("Could not find popular items: %v\n", err)
+		return recommendations, apperrors.NewInternal()
+	}
+	return recommendations, nil
+}
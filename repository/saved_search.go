@@ -0,0 +1,155 @@
+package repository
+
+import (
+	"errors"
+	"log"
+	"strconv"
+	"time"
+
+	"swap/apperrors"
+	"swap/models"
+
+	"gorm.io/gorm"
+)
+
+
+type savedSearchRepository struct {
+	DB *gorm.DB
+}
+
+
+func NewSavedSearchRepository(db *gorm.DB) models.ISavedSearchRepository {
+	return &savedSearchRepository{
+		DB: db,
+	}
+}
+
+
+func (r *savedSearchRepository) CreateSavedSearch(search *models.SavedSearch) (*models.SavedSearch, error) {
+	if search.CategoryName != "" {
+		category, err := findCategory(r.DB, search.CategoryName)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, apperrors.NewBadRequest("Invalid category: " + search.CategoryName)
+			}
+			return nil, apperrors.NewInternal()
+		}
+		search.CategoryId = &category.ID
+		search.CategoryName = category.Name
+	}
+
+	// Only items listed from now on are new to this search
+	search.LastAlertedAt = time.Now()
+
+	if err := r.DB.Create(search).Error; err != nil {
+		log.Printf("Could not save search for user %d: %v\n", search.UserId, err)
+		return nil, apperrors.NewInternal()
+	}
+	return search, nil
+}
+
+
+func (r *savedSearchRepository) GetSavedSearches(userId uint, request models.PageRequest) ([]models.SavedSearch, *models.Page, error) {
+	var searches []models.SavedSearch
+
+	page, err := findPage(r.DB.Where("user_id = ?", userId), "id", request, &searches)
+	if err != nil {
+		return searches, nil, err
+	}
+	return searches, page, nil
+}
+
+
+func (r *savedSearchRepository) DeleteSavedSearch(userId, searchId uint) error {
+	result := r.DB.Where("id = ? AND user_id = ?", searchId, userId).Delete(&models.SavedSearch{})
+	if result.Error != nil {
+		return apperrors.NewInternal()
+	}
+	if result.RowsAffected == 0 {
+		return apperrors.NewNotFound("saved search", strconv.Itoa(int(searchId)))
+	}
+	return nil
+}
+
+
+func (r *savedSearchRepository) GetInstantMatches(item *models.Item) ([]models.SavedSearch, error) {
+	var searches []models.SavedSearch
+
+	query := r.DB.Model(&models.SavedSearch{}).
+		Select("saved_searches.*").
+		Joins("JOIN items ON items.id = ?", item.ID).
+		Where("saved_searches.frequency = ?", models.InstantAlerts).
+		Where("saved_searches.user_id <> items.owner_id").
+		Where("saved_searches.keywords = '' OR items.search_vector @@ websearch_to_tsquery('english', saved_searches.keywords)").
+		Where("saved_searches.min_prize IS NULL OR items.prize >= saved_searches.min_prize").
+		Where("saved_searches.max_prize IS NULL OR items.prize <= saved_searches.max_prize")
+
+	// A search on a category also covers its subcategories
+	if item.CategoryId != nil {
+		categoryIds, err := ancestorCategoryIds(r.DB, *item.CategoryId)
+		if err != nil {
+			return searches, apperrors.NewInternal()
+		}
+		query = query.Where("saved_searches.category_id IS NULL OR saved_searches.category_id IN ?", categoryIds)
+	} else {
+		query = query.Where("saved_searches.category_id IS NULL")
+	}
+
+	if err := query.Find(&searches).Error; err != nil {
+		log.Printf("Could not match saved searches for item %d: %v\n", item.ID, err)
+		return searches, apperrors.NewInternal()
+	}
+	return searches, nil
+}
+
+
+func (r *savedSearchRepository) GetDueDigests(cutoff time.Time) ([]models.SavedSearch, error) {
+	var searches []models.SavedSearch
+
+	err := r.DB.Where("frequency = ? AND last_alerted_at <= ?", models.DailyAlerts, cutoff).
+		Order("id").
+		Find(&searches).Error
+	if err != nil {
+		return searches, apperrors.NewInternal()
+	}
+	return searches, nil
+}
+
+
+func (r *savedSearchRepository) GetNewMatches(search *models.SavedSearch, limit int) ([]models.Item, error) {
+	var items []models.Item
+
+	query := r.DB.Where("created_at > ? AND sold = ? AND owner_id <> ?", search.LastAlertedAt, false, search.UserId)
+
+	if search.Keywords != "" {
+		query = query.Where("search_vector @@ websearch_to_tsquery('english', ?)", search.Keywords)
+	}
+	if search.MinPrize != nil {
+		query = query.Where("prize >= ?", *search.MinPrize)
+	}
+	if search.MaxPrize != nil {
+		query = query.Where("prize <= ?", *search.MaxPrize)
+	}
+	if search.CategoryId != nil {
+		categoryIds, err := descendantCategoryIds(r.DB, *search.CategoryId)
+		if err != nil {
+			return items, apperrors.NewInternal()
+		}
+		query = query.Where("category_id IN ?", categoryIds)
+	}
+
+	if err := query.Order("id").Limit(limit).Find(&items).Error; err != nil {
+		log.Printf("Could not find new matches for saved search %d: %v\n", search.ID, err)
+		return items, apperrors.NewInternal()
+	}
+	return items, nil
+}
+
+
+func (r *savedSearchRepository) MarkAlerted(searchId uint, at time.Time) error {
+	err := r.DB.Model(&models.SavedSearch{}).Where("id = ?", searchId).Update("last_alerted_at", at).Error
+	if err != nil {
+		return apperrors.NewInternal()
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+
+	"swap/apperrors"
+	"swap/models"
+
+	"gorm.io/gorm"
+)
+
+
+type suggestionRepository struct {
+	DB *gorm.DB
+}
+
+
+func NewSuggestionRepository(db *gorm.DB) models.ISuggestionRepository {
+	return &suggestionRepository{
+		DB: db,
+	}
+}
+
+
+// SuggestItemNames matches unsold items whose name, or any word in it, starts
+// with prefix. Whole-name prefix matches come first, then shorter names.
+func (r *suggestionRepository) SuggestItemNames(ctx context.Context, prefix string, limit int) ([]models.Suggestion, error) {
+	var suggestions []models.Suggestion
+	pattern := likePrefix(prefix)
+
+	err := r.DB.WithContext(ctx).Model(&models.Item{}).
+		Select("id, name AS value").
+		Where("sold = ? AND expired = ?", false, false).
+		Where("lower(name) LIKE ? OR name ILIKE ?", pattern, "% "+pattern).
+		Order(gorm.Expr("lower(name) LIKE ? DESC, length(name), name", pattern)).
+		Limit(limit).
+		Scan(&suggestions).Error
+
+	return suggestions, suggestionError(err, "item names")
+}
+
+
+func (r *suggestionRepository) SuggestCategories(ctx context.Context, prefix string, limit int) ([]models.Suggestion, error) {
+	var suggestions []models.Suggestion
+
+	err := r.DB.WithContext(ctx).Model(&models.Category{}).
+		Select("id, name AS value").
+		Where("ban = ?", false).
+		Where("lower(name) LIKE ?", likePrefix(prefix)).
+		Order("length(name), name").
+		Limit(limit).
+		Scan(&suggestions).Error
+
+	return suggestions, suggestionError(err, "categories")
+}
+
+
+func (r *suggestionRepository) SuggestUserNames(ctx context.Context, prefix string, limit int) ([]models.Suggestion, error) {
+	var suggestions []models.Suggestion
+
+	err := r.DB.WithContext(ctx).Model(&models.User{}).
+		Select("id, user_name AS value").
+		Where("lower(user_name) LIKE ?", likePrefix(prefix)).
+		Order("length(user_name), user_name").
+		Limit(limit).
+		Scan(&suggestions).Error
+
+	return suggestions, suggestionError(err, "user names")
+}
+
+
+// likePrefix turns user input into a lower case LIKE pattern matching values
+// that start with it, escaping the LIKE wildcards
+func likePrefix(prefix string) string {
+	escaped := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(strings.ToLower(prefix))
+	return escaped + "%"
+}
+
+
+func suggestionError(err error, kind string) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return err
+	}
+	log.Printf("Could not suggest %s: %v\n", kind, err)
+	return apperrors.NewInternal()
+}
@@ -26,6 +26,22 @@ func NewSwapRepository(db *gorm.DB) models.ISwapRepository{
 }
 
 
+func (r *swapRepository) GetSwapRequestById(id uint) (*models.SwapRequest, error) {
+	request := &models.SwapRequest{}
+	swapId := strconv.Itoa(int(id))
+
+	if err := r.DB.Where("id = ?", id).First(&request).Error; err != nil {
+		log.Printf("Could not find swap request with ID %d\n", id)
+
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperrors.NewNotFound("swap request", swapId)
+		}
+		return nil, apperrors.NewInternal()
+	}
+	return request, nil
+}
+
+
 func (r *swapRepository) InitiateSwapRequest(id1, id2, initiatorId uint) (*models.SwapRequest, error) {
 	item1 := &models.Item{}
 	item1Id := strconv.Itoa(int(id1))
@@ -57,6 +73,14 @@ func (r *swapRepository) InitiateSwapRequest(id1, id2, initiatorId uint) (*model
 		return nil, apperrors.NewBadRequest("Item has already been sold")
 	} 
 
+	if item1.Giveaway || item2.Giveaway {
+		return nil, apperrors.NewBadRequest("Give-away items cannot be swapped")
+	}
+
+	if item1.Expired || item2.Expired {
+		return nil, apperrors.NewBadRequest("Listing has expired")
+	}
+
 	if item1.OwnerId != initiatorId {
 		return nil, apperrors.NewBadRequest("You must be the owner of first item")
 	}
@@ -73,6 +97,8 @@ func (r *swapRepository) InitiateSwapRequest(id1, id2, initiatorId uint) (*model
 			swapRequest.Item2Id = item2.ID
 			swapRequest.OwnerId = item2.OwnerId
 			swapRequest.InitiatorId = initiatorId
+			swapRequest.Item1Revision = item1.Revision
+			swapRequest.Item2Revision = item2.Revision
 			swapRequest.Status = "PENDING"
 
 
@@ -91,7 +117,7 @@ func (r *swapRepository) InitiateSwapRequest(id1, id2, initiatorId uint) (*model
 			return swapRequest, apperrors.NewBadRequest("You have already initiated a swap request with this item")
 		}
 
-		if err := r.DB.Model(&swapRequest).Updates(models.SwapRequest{Item1Id: item1.ID, Status: "PENDING"}).Error; err != nil {
+		if err := r.DB.Model(&swapRequest).Updates(models.SwapRequest{Item1Id: item1.ID, Item1Revision: item1.Revision, Item2Revision: item2.Revision, Status: "PENDING"}).Error; err != nil {
 			return nil, apperrors.NewBadRequest("Failed to update swap")
 		}
 		return swapRequest, nil
@@ -100,13 +126,14 @@ func (r *swapRepository) InitiateSwapRequest(id1, id2, initiatorId uint) (*model
 
 
 
-func (r *swapRepository) GetPendingSwapRequests(ownerId int, limit, page int) ([]models.EnrichedSwapRequest, error) {
+func (r *swapRepository) GetPendingSwapRequests(ownerId int, request models.PageRequest) ([]models.EnrichedSwapRequest, *models.Page, error) {
 	var requests []models.SwapRequest
 	var enrichedRequests []models.EnrichedSwapRequest
 
-	if err := r.DB.Where("owner_id = ? AND status = ?", ownerId, "PENDING").Find(&requests).Error; err != nil {
+	page, err := findPage(r.DB.Where("owner_id = ? AND status = ?", ownerId, "PENDING"), "id", request, &requests)
+	if err != nil {
 		log.Print("Unable to retrieve pending swap requests")
-		return enrichedRequests, apperrors.NewBadRequest("Unable to retrieve pending swap requests")
+		return enrichedRequests, nil, err
 	}
 
 	for _, swap := range requests {
@@ -116,17 +143,17 @@ func (r *swapRepository) GetPendingSwapRequests(ownerId int, limit, page int) ([
 
 		if err := r.DB.Where("id = ?", swap.Item1Id).First(&item1).Error; err != nil {
 			log.Print("Unable to get destination item")
-			return nil, apperrors.NewBadRequest("Unable to get destination item")
+			return nil, nil, apperrors.NewBadRequest("Unable to get destination item")
 		}
 
 		if err := r.DB.Where("id = ?", swap.Item2Id).First(&item2).Error; err != nil {
 			log.Print("Unable to get source item")
-			return nil, apperrors.NewBadRequest("Unable to get source item")
+			return nil, nil, apperrors.NewBadRequest("Unable to get source item")
 		}
 
 		if err := r.DB.Where("id = ?", swap.InitiatorId).First(&initiator).Error; err != nil {
 			log.Print("Unable to get swap initiator")
-			return nil, apperrors.NewBadRequest("Unable to get swap initiator")
+			return nil, nil, apperrors.NewBadRequest("Unable to get swap initiator")
 		}
 
 		enrichedRequests = append(enrichedRequests, models.EnrichedSwapRequest{
@@ -160,7 +187,7 @@ func (r *swapRepository) GetPendingSwapRequests(ownerId int, limit, page int) ([
 			CreatedAt 		:	swap.CreatedAt,
 		})
 	}
-	return enrichedRequests, nil
+	return enrichedRequests, page, nil
 }
 
 
@@ -423,6 +450,7 @@ func (r *swapRepository) AssignTransaction(transaction1, transaction2 *models.Tr
 	transaction1.PhoneNumber = owner1.PhoneNumber
 	transaction1.OwnerId = owner1.ID
 	transaction1.ItemId = request.Item2Id
+	transaction1.ItemRevision = request.Item2Revision
 	transaction1.ItemName = item2.Name
 	transaction1.Bought = false
 	transaction1.Swapped = true
@@ -435,6 +463,7 @@ func (r *swapRepository) AssignTransaction(transaction1, transaction2 *models.Tr
 	transaction2.PhoneNumber = owner2.PhoneNumber
 	transaction2.OwnerId = owner2.ID
 	transaction2.ItemId = request.Item1Id
+	transaction2.ItemRevision = request.Item1Revision
 	transaction2.ItemName = item1.Name
 	transaction2.Bought = false
 	transaction2.Swapped = true
@@ -466,25 +495,26 @@ func (r *swapRepository) AssignTransaction(transaction1, transaction2 *models.Tr
 
 
 
-func (r *swapRepository) GetAllIncompleteSwapByOwnerId(ownerId int, limit, page int) ([]models.IncompleteSwaps, error) {
+func (r *swapRepository) GetAllIncompleteSwapByOwnerId(ownerId int, request models.PageRequest) ([]models.IncompleteSwaps, *models.Page, error) {
 	var incompleteSwaps []models.IncompleteSwaps
 	var requests []models.SwapRequest
 
-	if err := r.DB.Where("owner_id = ?", ownerId).Find(&requests).Error; err != nil {
-		return incompleteSwaps, apperrors.NewBadRequest("You have no incomplete swaps")
+	page, err := findPage(r.DB.Where("owner_id = ?", ownerId), "id", request, &requests)
+	if err != nil {
+		return incompleteSwaps, nil, err
 	}
 
 	for _, swap := range requests {
 		item1 := &models.Item{}
 
 		if err := r.DB.Where("id = ?", swap.Item1Id).First(&item1).Error; err != nil {
-			return nil, apperrors.NewBadRequest("Could not retrieve item")
+			return nil, nil, apperrors.NewBadRequest("Could not retrieve item")
 		}
 
 		item2 := &models.Item{}
 
 		if err := r.DB.Where("id = ?", swap.Item2Id).First(&item2).Error; err != nil {
-			return nil, apperrors.NewBadRequest("Could not retrieve item")
+			return nil, nil, apperrors.NewBadRequest("Could not retrieve item")
 		}
 
 		balanceOwed := item1.Prize - item2.Prize
@@ -501,7 +531,7 @@ func (r *swapRepository) GetAllIncompleteSwapByOwnerId(ownerId int, limit, page
 		})
 	}
 
-	return incompleteSwaps, nil
+	return incompleteSwaps, page, nil
 }
 
 
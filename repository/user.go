@@ -129,14 +129,17 @@ func (r *userRepository) CreateOneTimePassword(user *models.User, password strin
 }
 
 
-func (r *userRepository) Search(term string, limit, page int) ([]models.User, error) {
+func (r *userRepository) Search(term string, request models.PageRequest) ([]models.User, *models.Page, error) {
 	var users []models.User
 
-	err := r.DB.Select("email", "userName", "phoneNumber", "bvn", "ID", "UUID").Where("email like ?", "%"+term+"%").Or("user_name = ?", "%"+term+"%").Find(&users)
-	if err.Error != nil {
-		return users, apperrors.NewInternal()
+	query := r.DB.Select("email", "user_name", "phone_number", "ID", "UUID").
+		Where("email like ? OR user_name like ?", "%"+term+"%", "%"+term+"%")
+
+	page, err := findPage(query, "id", request, &users)
+	if err != nil {
+		return users, nil, err
 	}
-	return users, nil
+	return users, page, nil
 }
 
 
@@ -221,21 +224,22 @@ func (r *userRepository) UpdatePassword(id uint, password string) error {
 }
 
 
-func (r *userRepository) GetUserTransactions(userId, limit, page int) ([]models.Transactions, error) {
+func (r *userRepository) GetUserTransactions(userId int, request models.PageRequest) ([]models.Transactions, *models.Page, error) {
 	var transactions []models.Transactions
 	foundUser, _ := r.GetUserById(userId)
 
 	if foundUser == nil {
 		log.Print("Could not find user with provided id")
-		return transactions, apperrors.NewBadRequest("Could not find user with provided id")
+		return transactions, nil, apperrors.NewBadRequest("Could not find user with provided id")
 	}
 
-	if err := r.DB.Where("owner_id = ?", userId).Find(&transactions).Error; err != nil {
+	page, err := findPage(r.DB.Where("owner_id = ?", userId), "id", request, &transactions)
+	if err != nil {
 		log.Print("Could not find user transactions\n")
-		return transactions, apperrors.NewBadRequest("Could not find user transaction")
+		return transactions, nil, err
 	}
 
-	return transactions, nil
+	return transactions, page, nil
 }
 
 
@@ -254,4 +258,19 @@ func (r userRepository) GetUserByItemId(id int) (*models.User, error) {
 	}
 
 	return user, nil
-}
\ No newline at end of file
+}
+
+
+func (r *userRepository) UpdateUserRole(userId uint, role string) error {
+	result := r.DB.Model(&models.User{}).Where("id = ?", userId).Update("role", role)
+
+	if result.Error != nil {
+		log.Printf("Could not update role of user %d: %v\n", userId, result.Error)
+		return apperrors.NewInternal()
+	}
+
+	if result.RowsAffected == 0 {
+		return apperrors.NewNotFound("user ID", strconv.Itoa(int(userId)))
+	}
+	return nil
+}
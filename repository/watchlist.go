@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"errors"
+	"log"
+	"strconv"
+
+	"swap/apperrors"
+	"swap/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+
+type watchlistRepository struct {
+	DB *gorm.DB
+}
+
+
+func NewWatchlistRepository(db *gorm.DB) models.IWatchlistRepository {
+	return &watchlistRepository{
+		DB: db,
+	}
+}
+
+
+// WatchItem adds the item to the user's watchlist. Watching an item twice is not an error.
+func (r *watchlistRepository) WatchItem(userId, itemId uint) error {
+	item := &models.Item{}
+	if err := r.DB.Select("id", "owner_id").Where("id = ?", itemId).First(item).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return apperrors.NewNotFound("item ID", strconv.Itoa(int(itemId)))
+		}
+		return apperrors.NewInternal()
+	}
+
+	if item.OwnerId == userId {
+		return apperrors.NewBadRequest("Cannot watch own item")
+	}
+
+	watch := &models.Watch{UserId: userId, ItemId: itemId}
+	if err := r.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(watch).Error; err != nil {
+		log.Printf("Could not add item %d to watchlist of user %d: %v\n", itemId, userId, err)
+		return apperrors.NewInternal()
+	}
+	return nil
+}
+
+
+func (r *watchlistRepository) UnwatchItem(userId, itemId uint) error {
+	result := r.DB.Where("user_id = ? AND item_id = ?", userId, itemId).Delete(&models.Watch{})
+	if result.Error != nil {
+		return apperrors.NewInternal()
+	}
+	if result.RowsAffected == 0 {
+		return apperrors.NewNotFound("watched item ID", strconv.Itoa(int(itemId)))
+	}
+	return nil
+}
+
+
+func (r *watchlistRepository) GetWatchedItems(userId uint, request models.PageRequest) ([]models.Item, *models.Page, error) {
+	var items []models.Item
+
+	query := r.DB.Joins("JOIN watches ON watches.item_id = items.id").Where("watches.user_id = ?", userId)
+	page, err := findPage(query, "items.id", request, &items)
+	if err != nil {
+		return items, nil, err
+	}
+	return items, page, nil
+}
+
+
+func (r *watchlistRepository) GetWatcherIds(itemId uint) ([]uint, error) {
+	var userIds []uint
+
+	if err := r.DB.Model(&models.Watch{}).Where("item_id = ?", itemId).Pluck("user_id", &userIds).Error; err != nil {
+		return userIds, apperrors.NewInternal()
+	}
+	return userIds, nil
+}
@@ -0,0 +1,109 @@
+package services
+
+import (
+	"log"
+	"time"
+
+	"swap/apperrors"
+	"swap/models"
+)
+
+
+type analyticsService struct {
+	AnalyticsRepository models.IAnalyticsRepository
+	ItemRepository      models.IItemRepository
+}
+
+
+func NewAnalyticsService(AnalyticsRepository models.IAnalyticsRepository, ItemRepository models.IItemRepository) models.IAnalyticsService {
+	return &analyticsService{
+		AnalyticsRepository: AnalyticsRepository,
+		ItemRepository:      ItemRepository,
+	}
+}
+
+
+// RecordView counts a view of an item. Failing to count one must never fail
+// the page being viewed, so errors are only logged.
+func (s *analyticsService) RecordView(itemId uint, viewerKey string) {
+	if err := s.AnalyticsRepository.RecordView(itemId, viewerKey, models.Day(time.Now())); err != nil {
+		log.Printf("Could not record view of item %d: %v\n", itemId, err)
+	}
+}
+
+
+// GetItemAnalytics returns the item's stats for every day from from to to,
+// including days without any activity. Only the owner and admins may see them.
+func (s *analyticsService) GetItemAnalytics(actor models.Actor, itemId int, from, to time.Time) (*models.ItemAnalytics, error) {
+	item, err := s.ItemRepository.GetItemById(itemId)
+	if err != nil {
+		return nil, err
+	}
+	if item.ID == 0 {
+		return nil, apperrors.NewNotFound("item", "")
+	}
+
+	if err := authorizeItem(actor, statsAction, item); err != nil {
+		return nil, err
+	}
+
+	from, to = models.Day(from), models.Day(to)
+	if from.After(to) {
+		return nil, apperrors.NewBadRequest("from cannot be later than to")
+	}
+	if to.Sub(from) > models.MaxAnalyticsDays*24*time.Hour {
+		return nil, apperrors.NewBadRequest("Analytics cover at most a year at a time")
+	}
+
+	stats, err := s.AnalyticsRepository.GetDailyStats(item.ID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	watchers, err := s.AnalyticsRepository.CountWatchers(item.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	analytics := &models.ItemAnalytics{
+		ItemId:   item.ID,
+		From:     from,
+		To:       to,
+		Watchers: watchers,
+		Daily:    []models.ItemDailyStat{},
+	}
+
+	byDay := map[time.Time]models.ItemDailyStat{}
+	for _, stat := range stats {
+		byDay[models.Day(stat.Day)] = stat
+	}
+
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		stat, ok := byDay[day]
+		if !ok {
+			stat = models.ItemDailyStat{ItemId: item.ID, Day: day}
+		}
+		analytics.Views += stat.Views
+		analytics.Watches += stat.Watches
+		analytics.SwapRequests += stat.SwapRequests
+		analytics.Daily = append(analytics.Daily, stat)
+	}
+
+	if analytics.Views > 0 {
+		analytics.Conversion = float64(analytics.SwapRequests) / float64(analytics.Views)
+	}
+	return analytics, nil
+}
+
+
+// AggregateDailyStats rolls recent views into daily stats, then drops raw views
+// old enough to never be recounted. Yesterday is always recounted so views
+// recorded just before midnight are not missed.
+func (s *analyticsService) AggregateDailyStats() error {
+	today := models.Day(time.Now())
+
+	if err := s.AnalyticsRepository.AggregateDailyStats(today.AddDate(0, 0, -1)); err != nil {
+		return err
+	}
+	return s.AnalyticsRepository.PruneViews(today.Add(-models.ViewRetention))
+}
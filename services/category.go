@@ -1,6 +1,7 @@
 package services
 
 import (
+	"swap/apperrors"
 	"swap/models"
 )
 
@@ -18,8 +19,8 @@ func NewCategoryService(CategoryRepository models.ICategoryRepository) models.IC
 
 
 
-func (s *categoryService) CreateCategory(name string) (*models.Category, error) {
-	return s.CategoryRepository.CreateCategory(name)
+func (s *categoryService) CreateCategory(name string, parentSlug string) (*models.Category, error) {
+	return s.CategoryRepository.CreateCategory(name, parentSlug)
 }
 
 
@@ -29,7 +30,43 @@ func (s *categoryService) DeleteCategory(name string) error {
 
 
 func (s *categoryService) GetAllValidCategories()([]models.Category, error) {
-	return s.CategoryRepository.GetAllValidCategories()
+	categories, err := s.CategoryRepository.GetAllValidCategories()
+	if err != nil {
+		return nil, err
+	}
+	return buildCategoryTree(categories), nil
+}
+
+
+// buildCategoryTree nests categories under their parents and returns the roots.
+// Categories whose parent is missing from the list (e.g. banned) are left out,
+// which hides the whole branch below a banned category.
+func buildCategoryTree(categories []models.Category) []models.Category {
+	children := map[uint][]models.Category{}
+	known := map[uint]bool{}
+
+	for _, category := range categories {
+		known[category.ID] = true
+	}
+
+	var roots []models.Category
+	for _, category := range categories {
+		if category.ParentId == nil {
+			roots = append(roots, category)
+		} else if known[*category.ParentId] {
+			children[*category.ParentId] = append(children[*category.ParentId], category)
+		}
+	}
+
+	var attach func(nodes []models.Category) []models.Category
+	attach = func(nodes []models.Category) []models.Category {
+		for i := range nodes {
+			nodes[i].Children = attach(children[nodes[i].ID])
+		}
+		return nodes
+	}
+
+	return attach(roots)
 }
 
 
@@ -48,6 +85,29 @@ func (s *categoryService) CheckStatus(name string) (bool, error) {
 }
 
 
-func(s *categoryService) GetAllItemsInCategory(id, limit, page int) ([]models.Item, error) {
-	return s.CategoryRepository.GetAllItemsInCategory(id, limit, page)
-}
\ No newline at end of file
+func(s *categoryService) GetAllItemsInCategory(id int, page models.PageRequest) ([]models.Item, *models.Page, error) {
+	return s.CategoryRepository.GetAllItemsInCategory(id, page)
+}
+
+
+func (s *categoryService) UpdateAttributeSchema(id int, schema models.AttributeSchema) error {
+	if err := schema.Check(); err != nil {
+		return apperrors.NewBadRequest(err.Error())
+	}
+	return s.CategoryRepository.UpdateAttributeSchema(id, schema)
+}
+
+
+func (s *categoryService) RenameCategory(name, newName string, dryRun bool) (*models.CategoryChangeReport, error) {
+	return s.CategoryRepository.RenameCategory(name, newName, dryRun)
+}
+
+
+func (s *categoryService) MergeCategory(source, target string, dryRun bool) (*models.CategoryChangeReport, error) {
+	return s.CategoryRepository.MergeCategory(source, target, dryRun)
+}
+
+
+func (s *categoryService) ReassignItems(itemIds []uint, target string, dryRun bool) (*models.CategoryChangeReport, error) {
+	return s.CategoryRepository.ReassignItems(itemIds, target, dryRun)
+}
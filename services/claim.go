@@ -0,0 +1,173 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+
+	"swap/apperrors"
+	"swap/models"
+)
+
+
+type claimService struct {
+	ClaimRepository     models.IClaimRepository
+	ItemRepository      models.IItemRepository
+	NotificationService models.INotificationService
+	Observers           []models.IItemObserver
+}
+
+
+func NewClaimService(ClaimRepository models.IClaimRepository, ItemRepository models.IItemRepository,
+	NotificationService models.INotificationService, observers ...models.IItemObserver) models.IClaimService {
+	return &claimService{
+		ClaimRepository:     ClaimRepository,
+		ItemRepository:      ItemRepository,
+		NotificationService: NotificationService,
+		Observers:           observers,
+	}
+}
+
+
+func (s *claimService) CreateClaim(userId, itemId uint, message string) (*models.Claim, error) {
+	claim, err := s.ClaimRepository.CreateClaim(itemId, userId, message)
+	if err != nil {
+		return nil, err
+	}
+
+	item, err := s.findItem(itemId)
+	if err != nil {
+		log.Printf("Could not load item %d to notify its owner: %v\n", itemId, err)
+		return claim, nil
+	}
+
+	s.notify(item.OwnerId, item, fmt.Sprintf("Someone wants %s", item.Name),
+		fmt.Sprintf("A new claim has been made on %s, which you are giving away", item.Name), false)
+	if claim.Status == models.ClaimChosen {
+		s.notifyChosen(claim.UserId, item)
+	}
+	return claim, nil
+}
+
+
+func (s *claimService) WithdrawClaim(userId, itemId uint) error {
+	promoted, err := s.ClaimRepository.WithdrawClaim(itemId, userId)
+	if err != nil {
+		return err
+	}
+
+	if promoted != nil {
+		if item, err := s.findItem(itemId); err == nil {
+			s.notifyChosen(promoted.UserId, item)
+		}
+	}
+	return nil
+}
+
+
+func (s *claimService) GetClaims(actor models.Actor, itemId uint, page models.PageRequest) ([]models.Claim, *models.Page, error) {
+	item, err := s.findItem(itemId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := authorizeItem(actor, claimsAction, item); err != nil {
+		return nil, nil, err
+	}
+	return s.ClaimRepository.GetClaims(itemId, page)
+}
+
+
+func (s *claimService) ChooseClaim(actor models.Actor, itemId, claimId uint) (*models.Claim, error) {
+	item, err := s.findItem(itemId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := authorizeItem(actor, claimsAction, item); err != nil {
+		return nil, err
+	}
+
+	claim, err := s.ClaimRepository.ChooseClaim(itemId, claimId)
+	if err != nil {
+		return nil, err
+	}
+	s.notifyChosen(claim.UserId, item)
+	return claim, nil
+}
+
+
+func (s *claimService) CompleteClaim(actor models.Actor, itemId uint) (*models.Claim, error) {
+	item, err := s.findItem(itemId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := authorizeItem(actor, claimsAction, item); err != nil {
+		return nil, err
+	}
+
+	outcome, err := s.ClaimRepository.CompleteClaim(itemId)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, userId := range outcome.DeclinedUserIds {
+		s.notify(userId, item, fmt.Sprintf("%s has been given away", item.Name),
+			fmt.Sprintf("%s went to another claimant", item.Name), false)
+	}
+
+	s.itemUpdated(*item)
+	return &outcome.Completed, nil
+}
+
+
+// itemUpdated passes the item before and after the hand-over to the observers
+func (s *claimService) itemUpdated(before models.Item) {
+	if len(s.Observers) == 0 {
+		return
+	}
+
+	after, err := s.findItem(before.ID)
+	if err != nil {
+		log.Printf("Could not reload item %d after give-away: %v\n", before.ID, err)
+		return
+	}
+
+	for _, observer := range s.Observers {
+		go observer.ItemUpdated(before, *after)
+	}
+}
+
+
+func (s *claimService) notifyChosen(userId uint, item *models.Item) {
+	s.notify(userId, item, fmt.Sprintf("%s is yours", item.Name),
+		fmt.Sprintf("You have been chosen to receive %s. Get in touch with the owner to arrange the hand-over.", item.Name), true)
+}
+
+
+func (s *claimService) notify(userId uint, item *models.Item, title, body string, email bool) {
+	notification := &models.Notification{
+		UserId: userId,
+		Kind:   models.ClaimNotification,
+		Title:  title,
+		Body:   body,
+		ItemId: &item.ID,
+	}
+	if err := s.NotificationService.Notify(notification, email); err != nil {
+		log.Printf("Could not notify user %d about claims on item %d: %v\n", userId, item.ID, err)
+	}
+}
+
+
+func (s *claimService) findItem(itemId uint) (*models.Item, error) {
+	item, err := s.ItemRepository.GetItemById(int(itemId))
+	if err != nil {
+		return nil, err
+	}
+
+	if item.ID == 0 {
+		return nil, apperrors.NewNotFound("item", strconv.Itoa(int(itemId)))
+	}
+	return item, nil
+}
@@ -0,0 +1,172 @@
+package services
+
+import (
+	"fmt"
+	"image"
+	"log"
+	"time"
+
+	"swap/apperrors"
+	"swap/models"
+	"swap/utils"
+)
+
+
+type duplicateDetector struct {
+	ListingFlagRepository models.IListingFlagRepository
+}
+
+
+func NewDuplicateDetector(ListingFlagRepository models.IListingFlagRepository) models.IDuplicateDetector {
+	return &duplicateDetector{
+		ListingFlagRepository: ListingFlagRepository,
+	}
+}
+
+
+// ScreenItem compares a new listing's text with the owner's unsold listings
+// and other users' recent ones
+func (d *duplicateDetector) ScreenItem(item *models.Item) ([]models.ListingFlag, error) {
+	candidates, err := d.ListingFlagRepository.GetCandidateItems(item.OwnerId, item.Name, time.Now().Add(-models.DuplicateLookback))
+	if err != nil {
+		return nil, err
+	}
+
+	shingles := listingShingles(*item)
+	var flags []models.ListingFlag
+
+	for _, candidate := range candidates {
+		if candidate.ID == item.ID {
+			continue
+		}
+
+		similarity := utils.Jaccard(shingles, listingShingles(candidate))
+		if similarity < models.DuplicateFlagSimilarity {
+			continue
+		}
+
+		if candidate.OwnerId == item.OwnerId && similarity >= models.DuplicateBlockSimilarity {
+			return nil, apperrors.NewBadRequest(fmt.Sprintf("This looks like a duplicate of your listing %d", candidate.ID))
+		}
+
+		flags = append(flags, models.ListingFlag{
+			MatchedItemId: candidate.ID,
+			Reason:        models.DuplicateTextFlag,
+			Similarity:    similarity,
+		})
+	}
+	return flags, nil
+}
+
+
+// ScreenImage compares an uploaded image with those of the owner's other
+// listings and other users' recent uploads
+func (d *duplicateDetector) ScreenImage(item *models.Item, img image.Image) (*int64, []models.ListingFlag, error) {
+	hash := utils.DifferenceHash(img)
+
+	candidates, err := d.ListingFlagRepository.GetCandidateImageHashes(item.OwnerId, item.ID, time.Now().Add(-models.DuplicateLookback))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var flags []models.ListingFlag
+	flagged := map[uint]bool{}
+
+	for _, candidate := range candidates {
+		distance := utils.HammingDistance(hash, uint64(candidate.Hash))
+		if distance > models.DuplicateImageFlagDistance || flagged[candidate.ItemId] {
+			continue
+		}
+
+		if candidate.OwnerId == item.OwnerId && distance <= models.DuplicateImageBlockDistance {
+			return nil, nil, apperrors.NewBadRequest(fmt.Sprintf("This image is already used by your listing %d", candidate.ItemId))
+		}
+
+		flagged[candidate.ItemId] = true
+		flags = append(flags, models.ListingFlag{
+			MatchedItemId: candidate.ItemId,
+			Reason:        models.DuplicateImageFlag,
+			Similarity:    1 - float64(distance)/64,
+		})
+	}
+
+	signed := int64(hash)
+	return &signed, flags, nil
+}
+
+
+// RecordFlags stores the flags raised while screening a listing once it has been saved
+func (d *duplicateDetector) RecordFlags(itemId uint, flags []models.ListingFlag) {
+	if len(flags) == 0 {
+		return
+	}
+
+	for i := range flags {
+		flags[i].ItemId = itemId
+		flags[i].Status = models.FlagOpen
+	}
+
+	if err := d.ListingFlagRepository.CreateFlags(flags); err != nil {
+		log.Printf("Could not flag item %d as a likely duplicate: %v\n", itemId, err)
+	}
+}
+
+
+// batchScreen finds listings that repeat an earlier one of the same batch,
+// such as the rows of an import, which are not in the database yet for
+// ScreenItem to find. Shingles are indexed so each listing is only compared
+// with the earlier ones it shares text with.
+type batchScreen struct {
+	shingles []map[uint64]struct{}
+	lines    []int
+	index    map[uint64][]int
+}
+
+
+func newBatchScreen() *batchScreen {
+	return &batchScreen{index: map[uint64][]int{}}
+}
+
+
+// Match returns the line of the first earlier listing that item duplicates
+func (b *batchScreen) Match(item models.Item) (int, bool) {
+	shingles := listingShingles(item)
+
+	shared := map[int]int{}
+	for shingle := range shingles {
+		for _, earlier := range b.index[shingle] {
+			shared[earlier]++
+		}
+	}
+
+	match := -1
+	for earlier, count := range shared {
+		similarity := float64(count) / float64(len(shingles)+len(b.shingles[earlier])-count)
+		if similarity >= models.DuplicateBlockSimilarity && (match < 0 || earlier < match) {
+			match = earlier
+		}
+	}
+
+	if match < 0 {
+		return 0, false
+	}
+	return b.lines[match], true
+}
+
+
+// Add indexes item, found on line, for later listings to be compared with
+func (b *batchScreen) Add(item models.Item, line int) {
+	shingles := listingShingles(item)
+	position := len(b.shingles)
+
+	b.shingles = append(b.shingles, shingles)
+	b.lines = append(b.lines, line)
+	for shingle := range shingles {
+		b.index[shingle] = append(b.index[shingle], position)
+	}
+}
+
+
+func listingShingles(item models.Item) map[uint64]struct{} {
+	return utils.Shingles(item.Name+" "+item.Description, models.DuplicateShingleSize)
+}
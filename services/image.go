@@ -1,42 +1,218 @@
 package services
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"log"
+	"strconv"
+	"strings"
+
+	"swap/apperrors"
 	"swap/models"
-	"swap/utils"
 )
 
 
 type imageService struct {
 	ImageRepository models.IImageRepository
+	ItemRepository  models.IItemRepository
+	BlobStore       models.IBlobStore
 }
 
 
-func NewImageService(imageRepository models.IImageRepository) *imageService {
+func NewImageService(imageRepository models.IImageRepository, itemRepository models.IItemRepository, blobStore models.IBlobStore) *imageService {
 	return &imageService{
 		ImageRepository: imageRepository,
+		ItemRepository:  itemRepository,
+		BlobStore:       blobStore,
+	}
+}
+
+
+func (s *imageService) OpenImage(imageId int, size string) (*models.ImageContent, error) {
+	image, err := s.ImageRepository.GetImage(imageId)
+	if err != nil {
+		return nil, err
+	}
+	return s.openSize(image, size)
+}
+
+
+func (s *imageService) OpenFirstImage(itemId int, size string) (*models.ImageContent, error) {
+	image, err := s.ImageRepository.GetFirstImage(itemId)
+	if err != nil {
+		return nil, err
 	}
+	return s.openSize(image, size)
+}
+
+
+func (s *imageService) ReadAllImagesByItemId(id int, page models.PageRequest) ([]models.Image, *models.Page, error) {
+	return s.ImageRepository.ReadAllImagesByItemId(id, page)
 }
 
 
-func (s *imageService) ReadFirstImageById(id int) ([]byte, error) {
-	return s.ImageRepository.ReadFirstImageById(id)
+// DeleteImage removes the record first so the image is never served without
+// its files; files that cannot be removed are only logged
+func (s *imageService) DeleteImage(actor models.Actor, itemId, imageId uint) error {
+	image, err := s.findItemImage(actor, itemId, imageId)
+	if err != nil {
+		return err
+	}
+
+	if err := s.ImageRepository.DeleteImage(image.ID); err != nil {
+		return err
+	}
+
+	// Images uploaded before keys were made unique may share their files
+	shared, err := s.ImageRepository.CountImagesByFile(image.FilePath, image.FileName)
+	if err != nil || shared > 0 {
+		log.Printf("Keeping the files of deleted image %d, which other images may still use\n", image.ID)
+		return nil
+	}
+
+	keys := []string{image.Key()}
+	for size := range models.ImageSizes {
+		keys = append(keys, image.VariantKey(size))
+	}
+	for _, key := range keys {
+		err := s.BlobStore.Delete(context.Background(), key)
+		if err != nil && !errors.Is(err, models.ErrBlobNotFound) {
+			log.Printf("Could not remove blob %s of deleted image %d: %v\n", key, image.ID, err)
+		}
+	}
+	return nil
 }
 
 
-func (s *imageService) ReadAllImagesByItemId(id, limit, page int) ([]models.Image, error) {
-	return s.ImageRepository.ReadAllImagesByItemId(id, limit, page)
+func (s *imageService) ReorderImages(actor models.Actor, itemId uint, imageIds []uint) ([]models.Image, error) {
+	item, err := s.findItem(itemId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := authorizeImage(actor, updateAction, item); err != nil {
+		return nil, err
+	}
+
+	if err := s.ImageRepository.ReorderImages(itemId, imageIds); err != nil {
+		return nil, err
+	}
+	return s.ImageRepository.GetImagesByItemId(itemId)
 }
 
 
-// func (s *imageService) UploadImage(itemId int, folderName, fileName string) error {
-// 	return s.ImageRepository.UploadImage(itemId, folderName, fileName)
-// }
+func (s *imageService) SetCoverImage(actor models.Actor, itemId, imageId uint) ([]models.Image, error) {
+	if _, err := s.findItemImage(actor, itemId, imageId); err != nil {
+		return nil, err
+	}
+
+	images, err := s.ImageRepository.GetImagesByItemId(itemId)
+	if err != nil {
+		return nil, err
+	}
+
+	imageIds := []uint{imageId}
+	for _, image := range images {
+		if image.ID != imageId {
+			imageIds = append(imageIds, image.ID)
+		}
+	}
 
-func (s *imageService) ReadImage(folderName, fileName string) ([]byte, error) {
-	return utils.ReadImage(folderName, fileName)
-}	
+	if err := s.ImageRepository.ReorderImages(itemId, imageIds); err != nil {
+		return nil, err
+	}
+	return s.ImageRepository.GetImagesByItemId(itemId)
+}
 
 
-func (s *imageService) ReadImageByPath(path string) ([]byte, error) {
-	return utils.ReadImageByPath(path)
-}	
+// findItemImage loads an image of an item the actor may rearrange, treating
+// images of other items as missing
+func (s *imageService) findItemImage(actor models.Actor, itemId, imageId uint) (*models.Image, error) {
+	item, err := s.findItem(itemId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := authorizeImage(actor, updateAction, item); err != nil {
+		return nil, err
+	}
+
+	image, err := s.ImageRepository.GetImage(int(imageId))
+	if err != nil {
+		return nil, err
+	}
+
+	if image.ItemId != itemId {
+		return nil, apperrors.NewNotFound("image", strconv.Itoa(int(imageId)))
+	}
+	return image, nil
+}
+
+
+func (s *imageService) findItem(itemId uint) (*models.Item, error) {
+	item, err := s.ItemRepository.GetItemById(int(itemId))
+	if err != nil {
+		return nil, err
+	}
+
+	if item.ID == 0 {
+		return nil, apperrors.NewNotFound("item", strconv.Itoa(int(itemId)))
+	}
+	return item, nil
+}
+
+
+// openSize opens the resized variant of an image, falling back to the
+// original while the variants are still being made or if one has gone missing
+func (s *imageService) openSize(image *models.Image, size string) (*models.ImageContent, error) {
+	if size == "" || image.VariantsAt == nil || !strings.HasPrefix(image.ContentType, "image/") {
+		return s.open(*image, image.Key())
+	}
+
+	variant := *image
+	variant.ContentType = image.VariantContentType()
+	if image.Checksum != "" {
+		variant.Checksum = image.Checksum + "-" + size
+	}
+
+	content, err := s.open(variant, image.VariantKey(size))
+	var missing *apperrors.Error
+	if errors.As(err, &missing) && missing.Type == apperrors.NotFound {
+		log.Printf("The %s variant of image %d is missing, serving the original\n", size, image.ID)
+		return s.open(*image, image.Key())
+	}
+	return content, err
+}
+
+
+// open fetches an image's blob. Stores that stream without seeking, like S3,
+// are read into memory so ranges can still be served. A record whose blob has
+// gone is reported as a missing image.
+func (s *imageService) open(image models.Image, key string) (*models.ImageContent, error) {
+	blob, info, err := s.BlobStore.Get(context.Background(), key)
+	if err != nil {
+		if errors.Is(err, models.ErrBlobNotFound) {
+			return nil, apperrors.NewNotFound("image", strconv.Itoa(int(image.ID)))
+		}
+		return nil, err
+	}
+
+	content := &models.ImageContent{Image: image, ModTime: info.ModTime, Closer: blob}
+	if seeker, ok := blob.(io.ReadSeeker); ok {
+		content.Content = seeker
+		return content, nil
+	}
+
+	data, err := ioutil.ReadAll(blob)
+	blob.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	content.Content = bytes.NewReader(data)
+	content.Closer = nil
+	return content, nil
+}
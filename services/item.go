@@ -1,23 +1,224 @@
 package services
 
 import (
+	"fmt"
+	"log"
+	"mime/multipart"
+	"strconv"
+	"time"
+
+	"swap/apperrors"
 	"swap/models"
+	"swap/utils"
 )
 
 
 type itemService struct {
-	ItemRepository models.IItemRepository
+	ItemRepository     models.IItemRepository
+	CategoryRepository models.ICategoryRepository
+	UserRepository     models.IUserRepository
+	Geocoder           models.IGeocoder
+	Detector           models.IDuplicateDetector
+	Thumbnails         models.IThumbnailService
+	Utils              *utils.Utils
+	Observers          []models.IItemObserver
 }
 
-func NewItemService(ItemRepository models.IItemRepository) models.IItemService {
+func NewItemService(ItemRepository models.IItemRepository, CategoryRepository models.ICategoryRepository, UserRepository models.IUserRepository, geocoder models.IGeocoder, detector models.IDuplicateDetector, thumbnails models.IThumbnailService, util *utils.Utils, observers ...models.IItemObserver) models.IItemService {
 	return &itemService{
 		ItemRepository: 	ItemRepository,
+		CategoryRepository: CategoryRepository,
+		UserRepository: 	UserRepository,
+		Geocoder: 			geocoder,
+		Detector: 			detector,
+		Thumbnails: 		thumbnails,
+		Utils: 				util,
+		Observers: 			observers,
 	}
 }
 
 
 func (s *itemService) RegisterItem(item *models.Item) (*models.Item, error) {
-	return s.ItemRepository.RegisterItem(item)
+	if err := s.prepareItem(item); err != nil {
+		return nil, err
+	}
+
+	flags, err := s.Detector.ScreenItem(item)
+	if err != nil {
+		return nil, err
+	}
+
+	if item.Coordinates() == nil {
+		placeItem(item, s.ownerCoordinates(item.OwnerId))
+	}
+
+	created, err := s.ItemRepository.RegisterItem(item)
+	if err != nil {
+		return nil, err
+	}
+
+	s.Detector.RecordFlags(created.ID, flags)
+	s.itemsRegistered(created)
+	return created, nil
+}
+
+
+// ImportItems registers the valid rows of a bulk import in batches and reports
+// on every row. A batch that fails to save fails all of its rows, but earlier
+// batches stay imported.
+func (s *itemService) ImportItems(ownerId uint, rows []models.ItemImportRow, dryRun bool) (*models.ImportReport, error) {
+	if len(rows) > models.MaxImportRows {
+		return nil, apperrors.NewBadRequest(fmt.Sprintf("Imports are limited to %d rows", models.MaxImportRows))
+	}
+
+	if _, err := s.UserRepository.GetUserById(int(ownerId)); err != nil {
+		return nil, err
+	}
+
+	report := &models.ImportReport{
+		DryRun: dryRun,
+		Total:  len(rows),
+		Rows:   make([]models.ImportRowResult, len(rows)),
+	}
+
+	// Every item of an import belongs to the same owner, so geocode them once
+	var ownerLocation *models.Coordinates
+	located := false
+
+	var batch []*models.Item
+	var batchRows []int
+	var batchFlags [][]models.ListingFlag
+
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := s.ItemRepository.RegisterItems(batch); err != nil {
+			for _, i := range batchRows {
+				report.Rows[i].Error = "Could not save item"
+			}
+		} else {
+			for j, i := range batchRows {
+				report.Rows[i].ItemId = batch[j].ID
+				s.Detector.RecordFlags(batch[j].ID, batchFlags[j])
+			}
+			s.itemsRegistered(batch...)
+		}
+		batch, batchRows, batchFlags = nil, nil, nil
+	}
+
+	// Rows are screened against existing listings and, since a bulk import is
+	// the easiest way to list one item many times, against each other
+	screen := newBatchScreen()
+
+	for i, row := range rows {
+		report.Rows[i].Line = row.Line
+		if row.Error != "" {
+			report.Rows[i].Error = row.Error
+			continue
+		}
+
+		item := row.Item
+		item.OwnerId = ownerId
+		if err := s.prepareItem(item); err != nil {
+			report.Rows[i].Error = err.Error()
+			continue
+		}
+
+		if line, ok := screen.Match(*item); ok {
+			report.Rows[i].Error = fmt.Sprintf("This looks like a duplicate of line %d", line)
+			continue
+		}
+
+		flags, err := s.Detector.ScreenItem(item)
+		if err != nil {
+			report.Rows[i].Error = err.Error()
+			continue
+		}
+		screen.Add(*item, row.Line)
+
+		if item.Coordinates() == nil {
+			if !located {
+				ownerLocation, located = s.ownerCoordinates(ownerId), true
+			}
+			placeItem(item, ownerLocation)
+		}
+
+		if dryRun {
+			continue
+		}
+
+		batch = append(batch, item)
+		batchRows = append(batchRows, i)
+		batchFlags = append(batchFlags, flags)
+		if len(batch) == models.ImportBatchSize {
+			flush()
+		}
+	}
+	flush()
+
+	for _, row := range report.Rows {
+		if row.Error != "" {
+			report.Failed++
+		}
+	}
+	report.Imported = report.Total - report.Failed
+	return report, nil
+}
+
+
+// prepareItem checks a new item against its category, which must exist and
+// not be banned, and fills in the category it belongs to
+func (s *itemService) prepareItem(item *models.Item) error {
+	category, err := s.CategoryRepository.GetCategory(item.CategoryName)
+	if err != nil || category.Ban {
+		return apperrors.NewBadRequest("Invalid category: " + item.CategoryName)
+	}
+
+	if err := category.AttributeSchema.Validate(item.Attributes); err != nil {
+		return apperrors.NewBadRequest(err.Error())
+	}
+
+	item.CategoryName = category.Name
+	item.CategoryId = &category.ID
+	return nil
+}
+
+
+func (s *itemService) itemsRegistered(items ...*models.Item) {
+	for _, item := range items {
+		for _, observer := range s.Observers {
+			go observer.ItemRegistered(*item)
+		}
+	}
+}
+
+
+// ownerCoordinates geocodes the owner's location. Items are still listed when
+// it can't be geocoded, they just won't show up in radius searches.
+func (s *itemService) ownerCoordinates(ownerId uint) *models.Coordinates {
+	owner, err := s.UserRepository.GetUserById(int(ownerId))
+	if err != nil || owner.Location == "" {
+		return nil
+	}
+
+	coordinates, err := s.Geocoder.Geocode(owner.Location)
+	if err != nil {
+		log.Printf("Could not geocode location %q of user %d: %v\n", owner.Location, owner.ID, err)
+		return nil
+	}
+	return coordinates
+}
+
+
+// placeItem puts an item at the given coordinates, if any
+func placeItem(item *models.Item, coordinates *models.Coordinates) {
+	if coordinates == nil {
+		return
+	}
+	latitude, longitude := coordinates.Latitude, coordinates.Longitude
+	item.Latitude = &latitude
+	item.Longitude = &longitude
 }
 
 
@@ -26,28 +227,171 @@ func (s *itemService) GetItemById(id int) (*models.Item, error) {
 }
 
 
-func (s *itemService) GetItemsByCategory(category string, limit, page int) ([]models.Item, error){
-	return s.ItemRepository.GetItemsByCategory(category, limit, page)
+func (s *itemService) QueryItems(query models.ItemQuery, page models.PageRequest) ([]models.Item, *models.ItemFacets, *models.Page, error) {
+	if query.Near == nil && query.NearAddress != "" {
+		coordinates, err := s.Geocoder.Geocode(query.NearAddress)
+		if err != nil {
+			if apperrors.GetAppError(err, "").Type == apperrors.NotFound {
+				return nil, nil, nil, apperrors.NewBadRequest("Could not find location: " + query.NearAddress)
+			}
+			return nil, nil, nil, err
+		}
+		query.Near = coordinates
+	}
+
+	if query.Sort == models.SortDistance && query.Near == nil {
+		return nil, nil, nil, apperrors.NewBadRequest("Sorting by distance needs a location to search near")
+	}
+	return s.ItemRepository.QueryItems(query, page)
+}
+
+
+func (s *itemService) UpdateItem(actor models.Actor, item models.Item) error {
+	foundItem, err := s.findItem(int(item.ID))
+	if err != nil {
+		return err
+	}
+
+	if err := authorizeItem(actor, updateAction, foundItem); err != nil {
+		return err
+	}
+
+	// Prize is -1 when the update leaves it unchanged
+	if foundItem.Giveaway && item.Prize > 0 {
+		return apperrors.NewBadRequest("Give-away items cannot be priced")
+	}
+
+	if item.Attributes != nil {
+		attributes, err := s.mergeAttributes(foundItem, item.Attributes)
+		if err != nil {
+			return err
+		}
+		item.Attributes = attributes
+	}
+
+	if err := s.ItemRepository.UpdateItem(item); err != nil {
+		return err
+	}
+	s.itemUpdated(*foundItem)
+	return nil
+}
+
+
+// RelistItem restarts the listing of an unsold item. Without a new duration it
+// runs for as long as it did before; a new price may only be lower, but not free.
+func (s *itemService) RelistItem(actor models.Actor, itemId int, prize *float64, days int) error {
+	item, err := s.findItem(itemId)
+	if err != nil {
+		return err
+	}
+
+	if err := authorizeItem(actor, updateAction, item); err != nil {
+		return err
+	}
+
+	if item.Sold {
+		return apperrors.NewBadRequest("Item has already been sold")
+	}
+
+	if prize != nil {
+		if item.Giveaway {
+			return apperrors.NewBadRequest("Give-away items cannot be priced")
+		}
+		if *prize <= 0 {
+			return apperrors.NewBadRequest("A relisted price cannot be zero")
+		}
+		if *prize >= item.Prize {
+			return apperrors.NewBadRequest("A relisted price must be lower than the current one")
+		}
+	}
+
+	if days == 0 {
+		days = item.ListingDays
+	}
+
+	if err := s.ItemRepository.RelistItem(item.ID, prize, models.ListingExpiry(time.Now(), days)); err != nil {
+		return err
+	}
+	s.itemUpdated(*item)
+	return nil
 }
 
 
-func (s *itemService) GetUnsoldItemsByCategory(category string, limit, page int) ([]models.Item, error){
-	return s.ItemRepository.GetUnsoldItemsByCategory(category, limit, page)
+// itemUpdated reloads an item after a change and passes both versions to the observers
+func (s *itemService) itemUpdated(before models.Item) {
+	if len(s.Observers) == 0 {
+		return
+	}
+
+	after, err := s.ItemRepository.GetItemById(int(before.ID))
+	if err != nil {
+		log.Printf("Could not reload item %d after update: %v\n", before.ID, err)
+		return
+	}
+
+	for _, observer := range s.Observers {
+		go observer.ItemUpdated(before, *after)
+	}
 }
 
 
-func (s *itemService) UpdateItem(item models.Item) error {
-	return s.ItemRepository.UpdateItem(item)
+// mergeAttributes applies a partial attribute update on top of the item's
+// current values, where a null value clears an attribute, and validates the
+// result against the item's category schema
+func (s *itemService) mergeAttributes(item *models.Item, changes models.Attributes) (models.Attributes, error) {
+	merged := models.Attributes{}
+	for name, value := range item.Attributes {
+		merged[name] = value
+	}
+	for name, value := range changes {
+		if value == nil {
+			delete(merged, name)
+			continue
+		}
+		merged[name] = value
+	}
+
+	var schema models.AttributeSchema
+	if item.CategoryName != "" {
+		category, err := s.CategoryRepository.GetCategory(item.CategoryName)
+		if err != nil {
+			return nil, err
+		}
+		schema = category.AttributeSchema
+	}
+
+	if err := schema.Validate(merged); err != nil {
+		return nil, apperrors.NewBadRequest(err.Error())
+	}
+	return merged, nil
 }
 
 
-func (s *itemService) DeleteItem(itemId int) error {
+func (s *itemService) DeleteItem(actor models.Actor, itemId int) error {
+	foundItem, err := s.findItem(itemId)
+	if err != nil {
+		return err
+	}
+
+	if err := authorizeItem(actor, deleteAction, foundItem); err != nil {
+		return err
+	}
 	return s.ItemRepository.DeleteItem(itemId)
 }
 
 
 func (s *itemService) BuyItem(userId, itemId int, amount float64) (string, error) {
-	return s.ItemRepository.BuyItem(userId, itemId, amount)
+	foundItem, err := s.findItem(itemId)
+	if err != nil {
+		return "", err
+	}
+
+	receipt, err := s.ItemRepository.BuyItem(userId, itemId, amount)
+	if err != nil {
+		return "", err
+	}
+	s.itemUpdated(*foundItem)
+	return receipt, nil
 }
 
 
@@ -56,8 +400,8 @@ func (s *itemService) BuyItem(userId, itemId int, amount float64) (string, error
 // }
 
 
-func (s *itemService) GetItemsByOwnerId(ownerId uint, limit, page int) ([]models.Item, error) {
-	return s.ItemRepository.GetItemsByOwnerId(ownerId, limit, page)
+func (s *itemService) GetItemsByOwnerId(ownerId uint, page models.PageRequest) ([]models.Item, *models.Page, error) {
+	return s.ItemRepository.GetItemsByOwnerId(ownerId, page)
 }
 
 
@@ -66,11 +410,94 @@ func (s *itemService) GetItemByUUID(uuid string) (*models.Item, error) {
 }
 
 
-func (s *itemService) UpdateCategory(itemId int, categoryName string) error {
+func (s *itemService) UpdateCategory(actor models.Actor, itemId int, categoryName string) error {
+	foundItem, err := s.findItem(itemId)
+	if err != nil {
+		return err
+	}
+
+	if err := authorizeItem(actor, updateAction, foundItem); err != nil {
+		return err
+	}
 	return s.ItemRepository.UpdateCategory(itemId, categoryName)
 }
 
 
+func (s *itemService) UploadImage(actor models.Actor, itemId int, file *multipart.FileHeader) (string, error) {
+	foundItem, err := s.findItem(itemId)
+	if err != nil {
+		return "", err
+	}
+
+	if err := authorizeImage(actor, uploadAction, foundItem); err != nil {
+		return "", err
+	}
+
+	upload, err := utils.ReadImageUpload(file)
+	if err != nil {
+		return "", err
+	}
+
+	hash, flags, err := s.Detector.ScreenImage(foundItem, upload.Image)
+	if err != nil {
+		return "", err
+	}
+
+	image, err := s.Utils.UploadItemFile(itemId, file, upload, hash)
+	if err != nil {
+		return "", err
+	}
+
+	s.Detector.RecordFlags(foundItem.ID, flags)
+	s.Thumbnails.Enqueue(*image)
+	return image.Key(), nil
+}
+
+
+func (s *itemService) SearchItems(query string, page models.PageRequest) ([]models.ItemSearchHit, *models.Page, error) {
+	return s.ItemRepository.SearchItems(query, page)
+}
+
+
+// GetItemRevisions lists every revision of an item, oldest first, with the
+// fields each one changed
+func (s *itemService) GetItemRevisions(itemId int) ([]models.ItemRevisionDiff, error) {
+	item, err := s.findItem(itemId)
+	if err != nil {
+		return nil, err
+	}
+
+	revisions, err := s.ItemRepository.GetItemRevisions(item.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	diffs := make([]models.ItemRevisionDiff, len(revisions))
+	for i, revision := range revisions {
+		diffs[i] = models.ItemRevisionDiff{ItemRevision: revision, Changes: []models.FieldChange{}}
+		if i > 0 {
+			diffs[i].Changes = revision.Diff(revisions[i-1])
+		}
+	}
+	return diffs, nil
+}
+
+
+// findItem loads an item and reports a missing one as not found,
+// since the repository lookup returns an empty item instead
+func (s *itemService) findItem(itemId int) (*models.Item, error) {
+	item, err := s.ItemRepository.GetItemById(itemId)
+	if err != nil {
+		return nil, err
+	}
+
+	if item.ID == 0 {
+		return nil, apperrors.NewNotFound("item", strconv.Itoa(itemId))
+	}
+	return item, nil
+}
+
+
 // func (s *itemService) ReadImageByPath(path string) ([]byte, error) {
 // 	return utils.ReadImageByPath(path)
 // }	
@@ -0,0 +1,87 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"swap/models"
+)
+
+
+type listingService struct {
+	ItemRepository      models.IItemRepository
+	NotificationService models.INotificationService
+	Observers           []models.IItemObserver
+}
+
+
+func NewListingService(ItemRepository models.IItemRepository, NotificationService models.INotificationService,
+	observers ...models.IItemObserver) models.IListingService {
+	return &listingService{
+		ItemRepository:      ItemRepository,
+		NotificationService: NotificationService,
+		Observers:           observers,
+	}
+}
+
+
+func (s *listingService) SendExpiryReminders() error {
+	items, err := s.ItemRepository.GetItemsExpiringBefore(time.Now().Add(models.ExpiryReminderWindow))
+	if err != nil {
+		return err
+	}
+
+	var reminded []uint
+	for i := range items {
+		item := &items[i]
+
+		notification := &models.Notification{
+			UserId: item.OwnerId,
+			Kind:   models.ListingNotification,
+			Title:  fmt.Sprintf("Your listing of %s expires soon", item.Name),
+			Body: fmt.Sprintf("%s will be taken off the market on %s. Relist it to keep it listed, perhaps at a lower price.",
+				item.Name, item.ExpiresAt.Format("Jan 2 at 15:04")),
+			ItemId: &item.ID,
+		}
+		if err := s.NotificationService.Notify(notification, true); err != nil {
+			log.Printf("Could not remind owner of item %d about its expiry: %v\n", item.ID, err)
+			continue
+		}
+		reminded = append(reminded, item.ID)
+	}
+
+	if len(reminded) == 0 {
+		return nil
+	}
+	return s.ItemRepository.MarkReminded(reminded)
+}
+
+
+func (s *listingService) ExpireListings() error {
+	items, err := s.ItemRepository.ExpireItems(time.Now())
+	if err != nil {
+		return err
+	}
+
+	for _, after := range items {
+		item := after
+		notification := &models.Notification{
+			UserId: item.OwnerId,
+			Kind:   models.ListingNotification,
+			Title:  fmt.Sprintf("Your listing of %s has expired", item.Name),
+			Body:   fmt.Sprintf("%s is no longer listed. Relist it whenever you are ready to sell it again.", item.Name),
+			ItemId: &item.ID,
+		}
+		if err := s.NotificationService.Notify(notification, false); err != nil {
+			log.Printf("Could not tell owner of item %d about its expiry: %v\n", item.ID, err)
+		}
+
+		before := item
+		before.Expired = false
+		for _, observer := range s.Observers {
+			go observer.ItemUpdated(before, item)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package services
+
+import (
+	"swap/apperrors"
+	"swap/models"
+)
+
+
+type moderationService struct {
+	ListingFlagRepository models.IListingFlagRepository
+}
+
+
+func NewModerationService(ListingFlagRepository models.IListingFlagRepository) models.IModerationService {
+	return &moderationService{
+		ListingFlagRepository: ListingFlagRepository,
+	}
+}
+
+
+func (s *moderationService) GetFlags(status string, page models.PageRequest) ([]models.ListingFlag, *models.Page, error) {
+	if status == "" {
+		status = models.FlagOpen
+	}
+	return s.ListingFlagRepository.GetFlags(status, page)
+}
+
+
+// ReviewFlag either dismisses a flag or removes the flagged listing
+func (s *moderationService) ReviewFlag(reviewerId, flagId uint, status string) (*models.ListingFlag, error) {
+	if status != models.FlagDismissed && status != models.FlagRemoved {
+		return nil, apperrors.NewBadRequest("Status must be DISMISSED or REMOVED")
+	}
+	return s.ListingFlagRepository.ReviewFlag(flagId, reviewerId, status)
+}
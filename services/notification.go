@@ -0,0 +1,58 @@
+package services
+
+import (
+	"fmt"
+	"html"
+	"log"
+
+	"swap/models"
+	"swap/utils"
+)
+
+
+type notificationService struct {
+	NotificationRepository models.INotificationRepository
+	UserRepository         models.IUserRepository
+}
+
+
+func NewNotificationService(NotificationRepository models.INotificationRepository, UserRepository models.IUserRepository) models.INotificationService {
+	return &notificationService{
+		NotificationRepository: NotificationRepository,
+		UserRepository:         UserRepository,
+	}
+}
+
+
+// Notify saves the notification first so it is never lost to an email failure,
+// which is only logged
+func (s *notificationService) Notify(notification *models.Notification, email bool) error {
+	if err := s.NotificationRepository.CreateNotification(notification); err != nil {
+		return err
+	}
+
+	if !email {
+		return nil
+	}
+
+	user, err := s.UserRepository.GetUserById(int(notification.UserId))
+	if err != nil {
+		return err
+	}
+
+	body := fmt.Sprintf("<p>%s</p>", html.EscapeString(notification.Body))
+	if err := utils.SendEmailWithDefaultSender(user.Email, notification.Title, body); err != nil {
+		log.Printf("Could not email notification %d to user %d: %v\n", notification.ID, user.ID, err)
+	}
+	return nil
+}
+
+
+func (s *notificationService) GetNotifications(userId uint, unreadOnly bool, page models.PageRequest) ([]models.Notification, *models.Page, error) {
+	return s.NotificationRepository.GetNotifications(userId, unreadOnly, page)
+}
+
+
+func (s *notificationService) MarkRead(userId uint, notificationIds []uint) error {
+	return s.NotificationRepository.MarkRead(userId, notificationIds)
+}
@@ -0,0 +1,89 @@
+package services
+
+import (
+	"swap/apperrors"
+	"swap/models"
+)
+
+
+type action string
+
+const (
+	viewAction     action = "VIEW"
+	updateAction   action = "UPDATE"
+	deleteAction   action = "DELETE"
+	uploadAction   action = "UPLOAD"
+	acceptAction   action = "ACCEPT"
+	rejectAction   action = "REJECT"
+	completeAction action = "COMPLETE"
+	statsAction    action = "STATS"
+	claimsAction   action = "CLAIMS"
+	answerAction   action = "ANSWER"
+)
+
+
+// authorizeItem decides whether actor may perform action on item.
+// Owners may do anything with their items, admins may moderate them.
+func authorizeItem(actor models.Actor, act action, item *models.Item) error {
+	switch act {
+	case viewAction:
+		return nil
+	case updateAction, deleteAction, uploadAction, statsAction, claimsAction:
+		if actor.Admin || item.OwnerId == actor.ID {
+			return nil
+		}
+	case answerAction:
+		// Answers speak for the seller, so admins cannot give them
+		if item.OwnerId == actor.ID {
+			return nil
+		}
+	}
+	return apperrors.NewAuthorization(apperrors.Forbidden)
+}
+
+
+// authorizeImage decides whether actor may perform action on an image of item.
+// Images follow the ownership of the item they belong to, but only the owner
+// arranges or removes them; admins moderate by removing the whole listing.
+func authorizeImage(actor models.Actor, act action, item *models.Item) error {
+	switch act {
+	case viewAction:
+		return nil
+	case uploadAction:
+		return authorizeItem(actor, updateAction, item)
+	case updateAction, deleteAction:
+		if item.OwnerId == actor.ID {
+			return nil
+		}
+	}
+	return apperrors.NewAuthorization(apperrors.Forbidden)
+}
+
+
+// authorizeSwap decides whether actor may perform action on swap.
+// Only the owner of the requested item can accept, either party can complete,
+// and admins can reject any request as moderation.
+func authorizeSwap(actor models.Actor, act action, swap *models.SwapRequest) error {
+	isOwner := swap.OwnerId == actor.ID
+	isInitiator := swap.InitiatorId == actor.ID
+
+	switch act {
+	case viewAction:
+		if actor.Admin || isOwner || isInitiator {
+			return nil
+		}
+	case acceptAction:
+		if isOwner {
+			return nil
+		}
+	case rejectAction:
+		if actor.Admin || isOwner {
+			return nil
+		}
+	case completeAction:
+		if isOwner || isInitiator {
+			return nil
+		}
+	}
+	return apperrors.NewAuthorization(apperrors.Forbidden)
+}
@@ -0,0 +1,128 @@
+package services
+
+import (
+	"testing"
+
+	"swap/models"
+)
+
+
+var (
+	owner    = models.Actor{ID: 1}
+	stranger = models.Actor{ID: 2}
+	admin    = models.Actor{ID: 3, Admin: true}
+)
+
+
+type policyCase struct {
+	act      action
+	actor    models.Actor
+	allowed  bool
+}
+
+
+func checkPolicy(t *testing.T, name string, cases []policyCase, authorize func(models.Actor, action) error) {
+	t.Helper()
+	for _, tc := range cases {
+		err := authorize(tc.actor, tc.act)
+		if allowed := err == nil; allowed != tc.allowed {
+			t.Errorf("%s: actor %+v performing %s: allowed = %v, want %v", name, tc.actor, tc.act, allowed, tc.allowed)
+		}
+	}
+}
+
+
+func TestAuthorizeItem(t *testing.T) {
+	item := &models.Item{OwnerId: owner.ID}
+
+	cases := []policyCase{
+		{viewAction, owner, true},
+		{viewAction, stranger, true},
+		{viewAction, admin, true},
+		{updateAction, owner, true},
+		{updateAction, stranger, false},
+		{updateAction, admin, true},
+		{deleteAction, owner, true},
+		{deleteAction, stranger, false},
+		{deleteAction, admin, true},
+		{uploadAction, owner, true},
+		{uploadAction, stranger, false},
+		{uploadAction, admin, true},
+		{statsAction, owner, true},
+		{statsAction, stranger, false},
+		{statsAction, admin, true},
+		{claimsAction, owner, true},
+		{claimsAction, stranger, false},
+		{claimsAction, admin, true},
+		{answerAction, owner, true},
+		{answerAction, stranger, false},
+		{answerAction, admin, false},
+		{acceptAction, owner, false},
+		{acceptAction, stranger, false},
+		{acceptAction, admin, false},
+	}
+
+	checkPolicy(t, "item", cases, func(actor models.Actor, act action) error {
+		return authorizeItem(actor, act, item)
+	})
+}
+
+
+func TestAuthorizeImage(t *testing.T) {
+	item := &models.Item{OwnerId: owner.ID}
+
+	cases := []policyCase{
+		{viewAction, owner, true},
+		{viewAction, stranger, true},
+		{viewAction, admin, true},
+		{uploadAction, owner, true},
+		{uploadAction, stranger, false},
+		{uploadAction, admin, true},
+		{updateAction, owner, true},
+		{updateAction, stranger, false},
+		{updateAction, admin, false},
+		{deleteAction, owner, true},
+		{deleteAction, stranger, false},
+		{deleteAction, admin, false},
+		{statsAction, owner, false},
+		{statsAction, stranger, false},
+		{statsAction, admin, false},
+	}
+
+	checkPolicy(t, "image", cases, func(actor models.Actor, act action) error {
+		return authorizeImage(actor, act, item)
+	})
+}
+
+
+func TestAuthorizeSwap(t *testing.T) {
+	initiator := models.Actor{ID: 4}
+	swap := &models.SwapRequest{OwnerId: owner.ID, InitiatorId: initiator.ID}
+
+	cases := []policyCase{
+		{viewAction, owner, true},
+		{viewAction, initiator, true},
+		{viewAction, stranger, false},
+		{viewAction, admin, true},
+		{acceptAction, owner, true},
+		{acceptAction, initiator, false},
+		{acceptAction, stranger, false},
+		{acceptAction, admin, false},
+		{rejectAction, owner, true},
+		{rejectAction, initiator, false},
+		{rejectAction, stranger, false},
+		{rejectAction, admin, true},
+		{completeAction, owner, true},
+		{completeAction, initiator, true},
+		{completeAction, stranger, false},
+		{completeAction, admin, false},
+		{deleteAction, owner, false},
+		{deleteAction, initiator, false},
+		{deleteAction, stranger, false},
+		{deleteAction, admin, false},
+	}
+
+	checkPolicy(t, "swap", cases, func(actor models.Actor, act action) error {
+		return authorizeSwap(actor, act, swap)
+	})
+}
@@ -0,0 +1,132 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+
+	"swap/apperrors"
+	"swap/models"
+)
+
+
+type questionService struct {
+	QuestionRepository  models.IQuestionRepository
+	ItemRepository      models.IItemRepository
+	NotificationService models.INotificationService
+}
+
+
+func NewQuestionService(QuestionRepository models.IQuestionRepository, ItemRepository models.IItemRepository,
+	NotificationService models.INotificationService) models.IQuestionService {
+	return &questionService{
+		QuestionRepository:  QuestionRepository,
+		ItemRepository:      ItemRepository,
+		NotificationService: NotificationService,
+	}
+}
+
+
+func (s *questionService) AskQuestion(userId, itemId uint, body string) (*models.Question, error) {
+	item, err := s.findItem(itemId)
+	if err != nil {
+		return nil, err
+	}
+
+	if item.OwnerId == userId {
+		return nil, apperrors.NewBadRequest("Cannot ask a question about your own item")
+	}
+
+	question := &models.Question{ItemId: itemId, AskerId: userId, Body: body}
+	if err := s.QuestionRepository.CreateQuestion(question); err != nil {
+		return nil, err
+	}
+
+	notification := &models.Notification{
+		UserId: item.OwnerId,
+		Kind:   models.QuestionNotification,
+		Title:  fmt.Sprintf("New question about %s", item.Name),
+		Body:   body,
+		ItemId: &item.ID,
+	}
+	if err := s.NotificationService.Notify(notification, true); err != nil {
+		log.Printf("Could not notify owner of item %d about question %d: %v\n", item.ID, question.ID, err)
+	}
+	return question, nil
+}
+
+
+func (s *questionService) GetQuestions(actor models.Actor, itemId uint, page models.PageRequest) ([]models.Question, *models.Page, error) {
+	item, err := s.findItem(itemId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	answeredOnly := authorizeItem(actor, updateAction, item) != nil
+	return s.QuestionRepository.GetQuestions(itemId, answeredOnly, page)
+}
+
+
+func (s *questionService) AnswerQuestion(actor models.Actor, itemId, questionId uint, answer string) (*models.Question, error) {
+	item, err := s.findItem(itemId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := authorizeItem(actor, answerAction, item); err != nil {
+		return nil, err
+	}
+
+	question, err := s.QuestionRepository.GetQuestion(itemId, questionId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.QuestionRepository.AnswerQuestion(question, answer); err != nil {
+		return nil, err
+	}
+
+	notification := &models.Notification{
+		UserId: question.AskerId,
+		Kind:   models.QuestionNotification,
+		Title:  fmt.Sprintf("Your question about %s was answered", item.Name),
+		Body:   answer,
+		ItemId: &item.ID,
+	}
+	if err := s.NotificationService.Notify(notification, false); err != nil {
+		log.Printf("Could not notify user %d about answer to question %d: %v\n", question.AskerId, question.ID, err)
+	}
+	return question, nil
+}
+
+
+// DeleteQuestion lets owners and admins remove spam or abusive questions
+func (s *questionService) DeleteQuestion(actor models.Actor, itemId, questionId uint) error {
+	item, err := s.findItem(itemId)
+	if err != nil {
+		return err
+	}
+
+	if err := authorizeItem(actor, deleteAction, item); err != nil {
+		return err
+	}
+
+	question, err := s.QuestionRepository.GetQuestion(itemId, questionId)
+	if err != nil {
+		return err
+	}
+	return s.QuestionRepository.DeleteQuestion(question)
+}
+
+
+func (s *questionService) findItem(itemId uint) (*models.Item, error) {
+	item, err := s.ItemRepository.GetItemById(int(itemId))
+	if err != nil {
+		return nil, err
+	}
+
+	if item.ID == 0 {
+		return nil, apperrors.NewNotFound("item", strconv.Itoa(int(itemId)))
+	}
+	return item, nil
+}
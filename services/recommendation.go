@@ -0,0 +1,68 @@
+package services
+
+import (
+	"swap/models"
+)
+
+
+type recommendationService struct {
+	RecommendationRepository models.IRecommendationRepository
+}
+
+
+func NewRecommendationService(RecommendationRepository models.IRecommendationRepository) models.IRecommendationService {
+	return &recommendationService{
+		RecommendationRepository: RecommendationRepository,
+	}
+}
+
+
+// GetRecommendations returns items picked for the user, topped up with
+// popular items when the user's history doesn't yield enough
+func (s *recommendationService) GetRecommendations(userId uint, limit int) ([]models.Recommendation, error) {
+	if limit <= 0 {
+		limit = models.DefaultRecommendationLimit
+	}
+	if limit > models.MaxRecommendationLimit {
+		limit = models.MaxRecommendationLimit
+	}
+
+	recommendations, err := s.RecommendationRepository.GetRecommendations(userId, limit)
+	if err != nil {
+		return nil, err
+	}
+	if len(recommendations) >= limit {
+		return recommendations, nil
+	}
+
+	popular, err := s.RecommendationRepository.GetPopularItems(userId, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[uint]bool{}
+	for _, recommendation := range recommendations {
+		seen[recommendation.ID] = true
+	}
+	for _, item := range popular {
+		if len(recommendations) >= limit {
+			break
+		}
+		if seen[item.ID] {
+			continue
+		}
+		// Popular items rank below every personal recommendation
+		item.Score = 0
+		recommendations = append(recommendations, item)
+	}
+
+	if recommendations == nil {
+		recommendations = []models.Recommendation{}
+	}
+	return recommendations, nil
+}
+
+
+func (s *recommendationService) ComputeSimilarities() error {
+	return s.RecommendationRepository.ComputeSimilarities()
+}
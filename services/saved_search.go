@@ -0,0 +1,127 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"swap/models"
+)
+
+
+// digestLimit caps how many new listings one digest mentions
+const digestLimit = 20
+
+
+type savedSearchService struct {
+	SavedSearchRepository models.ISavedSearchRepository
+	NotificationService   models.INotificationService
+}
+
+
+func NewSavedSearchService(SavedSearchRepository models.ISavedSearchRepository, NotificationService models.INotificationService) models.ISavedSearchService {
+	return &savedSearchService{
+		SavedSearchRepository: SavedSearchRepository,
+		NotificationService:   NotificationService,
+	}
+}
+
+
+func (s *savedSearchService) CreateSavedSearch(search *models.SavedSearch) (*models.SavedSearch, error) {
+	return s.SavedSearchRepository.CreateSavedSearch(search)
+}
+
+
+func (s *savedSearchService) GetSavedSearches(userId uint, page models.PageRequest) ([]models.SavedSearch, *models.Page, error) {
+	return s.SavedSearchRepository.GetSavedSearches(userId, page)
+}
+
+
+func (s *savedSearchService) DeleteSavedSearch(userId, searchId uint) error {
+	return s.SavedSearchRepository.DeleteSavedSearch(userId, searchId)
+}
+
+
+// ItemRegistered alerts the owners of instant saved searches that a new item matches
+func (s *savedSearchService) ItemRegistered(item models.Item) {
+	searches, err := s.SavedSearchRepository.GetInstantMatches(&item)
+	if err != nil {
+		log.Printf("Could not match item %d against saved searches: %v\n", item.ID, err)
+		return
+	}
+
+	now := time.Now()
+	for _, search := range searches {
+		notification := &models.Notification{
+			UserId: search.UserId,
+			Kind:   models.SavedSearchNotification,
+			Title:  fmt.Sprintf("New match for your search %q", search.Name),
+			Body:   fmt.Sprintf("%s was just listed for $%.2f", item.Name, item.Prize),
+			ItemId: &item.ID,
+		}
+		if err := s.NotificationService.Notify(notification, true); err != nil {
+			log.Printf("Could not alert user %d about item %d: %v\n", search.UserId, item.ID, err)
+			continue
+		}
+		s.SavedSearchRepository.MarkAlerted(search.ID, now)
+	}
+}
+
+
+// ItemUpdated does nothing: saved searches only alert about new listings
+func (s *savedSearchService) ItemUpdated(before, after models.Item) {}
+
+
+// SendDailyDigests sends every daily saved search that is due a single
+// notification listing the items registered since its last digest
+func (s *savedSearchService) SendDailyDigests() error {
+	now := time.Now()
+
+	searches, err := s.SavedSearchRepository.GetDueDigests(now.Add(-models.DigestInterval))
+	if err != nil {
+		return err
+	}
+
+	for i := range searches {
+		search := &searches[i]
+
+		items, err := s.SavedSearchRepository.GetNewMatches(search, digestLimit)
+		if err != nil {
+			return err
+		}
+
+		if len(items) > 0 {
+			if err := s.NotificationService.Notify(digestNotification(search, items), true); err != nil {
+				log.Printf("Could not send digest for saved search %d: %v\n", search.ID, err)
+				continue
+			}
+		}
+
+		// Items registered while this run was in progress go into the next digest
+		if err := s.SavedSearchRepository.MarkAlerted(search.ID, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+
+func digestNotification(search *models.SavedSearch, items []models.Item) *models.Notification {
+	lines := make([]string, len(items))
+	for i, item := range items {
+		lines[i] = fmt.Sprintf("%s ($%.2f)", item.Name, item.Prize)
+	}
+
+	title := fmt.Sprintf("%d new listings for your search %q", len(items), search.Name)
+	if len(items) == 1 {
+		title = fmt.Sprintf("1 new listing for your search %q", search.Name)
+	}
+
+	return &models.Notification{
+		UserId: search.UserId,
+		Kind:   models.SavedSearchNotification,
+		Title:  title,
+		Body:   strings.Join(lines, ", "),
+	}
+}
@@ -0,0 +1,70 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"swap/models"
+)
+
+
+type suggestionService struct {
+	SuggestionRepository models.ISuggestionRepository
+}
+
+
+func NewSuggestionService(SuggestionRepository models.ISuggestionRepository) models.ISuggestionService {
+	return &suggestionService{
+		SuggestionRepository: SuggestionRepository,
+	}
+}
+
+
+type suggestFunc func(ctx context.Context, prefix string, limit int) ([]models.Suggestion, error)
+
+
+func (s *suggestionService) SuggestItemNames(prefix string, limit int) ([]models.Suggestion, error) {
+	return suggest(s.SuggestionRepository.SuggestItemNames, prefix, limit)
+}
+
+
+func (s *suggestionService) SuggestCategories(prefix string, limit int) ([]models.Suggestion, error) {
+	return suggest(s.SuggestionRepository.SuggestCategories, prefix, limit)
+}
+
+
+func (s *suggestionService) SuggestUserNames(prefix string, limit int) ([]models.Suggestion, error) {
+	return suggest(s.SuggestionRepository.SuggestUserNames, prefix, limit)
+}
+
+
+// suggest runs one lookup within the latency budget. Prefixes that are too
+// short, and lookups that run out of time, simply have no suggestions.
+func suggest(lookup suggestFunc, prefix string, limit int) ([]models.Suggestion, error) {
+	suggestions := []models.Suggestion{}
+
+	prefix = strings.TrimSpace(prefix)
+	if len([]rune(prefix)) < models.MinSuggestionPrefix {
+		return suggestions, nil
+	}
+
+	if limit <= 0 {
+		limit = models.DefaultSuggestionLimit
+	}
+	if limit > models.MaxSuggestionLimit {
+		limit = models.MaxSuggestionLimit
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), models.SuggestionTimeout)
+	defer cancel()
+
+	found, err := lookup(ctx, prefix, limit)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) || ctx.Err() != nil {
+			return suggestions, nil
+		}
+		return nil, err
+	}
+	return append(suggestions, found...), nil
+}
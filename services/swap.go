@@ -1,17 +1,24 @@
 package services
 
 import (
+	"log"
+
 	"swap/models"
 )
 
 type swapService struct {
 	SwapRepository models.ISwapRepository
+	ItemRepository models.IItemRepository
+	Observers      []models.IItemObserver
 }
 
 
-func NewSwapService(SwapRepository models.ISwapRepository) models.ISwapService {
+func NewSwapService(SwapRepository models.ISwapRepository, ItemRepository models.IItemRepository,
+	observers ...models.IItemObserver) models.ISwapService {
 	return &swapService{
 		SwapRepository: 	SwapRepository,
+		ItemRepository: 	ItemRepository,
+		Observers: 			observers,
 	}
 }
 
@@ -21,23 +28,98 @@ func (s *swapService) InitiateSwapRequest(item1Id, item2Id, initiatorId uint) (*
 }
 
 
-func (s *swapService) GetPendingSwapRequests(ownerId int, limit, page int) ([]models.EnrichedSwapRequest, error) {
-	return s.SwapRepository.GetPendingSwapRequests(ownerId, limit, page)
+func (s *swapService) GetPendingSwapRequests(ownerId int, page models.PageRequest) ([]models.EnrichedSwapRequest, *models.Page, error) {
+	return s.SwapRepository.GetPendingSwapRequests(ownerId, page)
+}
+
+
+func (s *swapService) RejectSwapRequest(actor models.Actor, swapId int) error {
+	swap, err := s.SwapRepository.GetSwapRequestById(uint(swapId))
+	if err != nil {
+		return err
+	}
+
+	if err := authorizeSwap(actor, rejectAction, swap); err != nil {
+		return err
+	}
+	return s.SwapRepository.RejectSwapRequest(int(swap.OwnerId), swapId)
+}
+
+
+func (s *swapService) AcceptSwapRequest(actor models.Actor, swapId int) (string, error) {
+	swap, err := s.SwapRepository.GetSwapRequestById(uint(swapId))
+	if err != nil {
+		return "", err
+	}
+
+	if err := authorizeSwap(actor, acceptAction, swap); err != nil {
+		return "", err
+	}
+
+	// Swaps of equally priced items complete as soon as they are accepted
+	before := s.loadItems(swap)
+	message, err := s.SwapRepository.AcceptSwapRequest(int(swap.OwnerId), swapId)
+	if err != nil {
+		return "", err
+	}
+	s.itemsUpdated(before)
+	return message, nil
 }
 
 
-func (s *swapService) RejectSwapRequest(ownerId, swapId int) error {
-	return s.SwapRepository.RejectSwapRequest(ownerId, swapId)
+func (s *swapService) CompleteSwapRequest(actor models.Actor, amount float64, swapId uint) (string, error) {
+	swap, err := s.SwapRepository.GetSwapRequestById(swapId)
+	if err != nil {
+		return "", err
+	}
+
+	if err := authorizeSwap(actor, completeAction, swap); err != nil {
+		return "", err
+	}
+
+	before := s.loadItems(swap)
+	message, err := s.SwapRepository.CompleteSwapRequest(actor.ID, amount, swapId)
+	if err != nil {
+		return "", err
+	}
+	s.itemsUpdated(before)
+	return message, nil
 }
 
 
-func (s *swapService) AcceptSwapRequest(ownerId, swapId int) (string, error) {
-	return s.SwapRepository.AcceptSwapRequest(ownerId, swapId)
+// loadItems loads both items of a swap so observers can be told how a
+// completed swap changed them
+func (s *swapService) loadItems(swap *models.SwapRequest) []models.Item {
+	var items []models.Item
+	if len(s.Observers) == 0 {
+		return items
+	}
+
+	for _, itemId := range []uint{swap.Item1Id, swap.Item2Id} {
+		item, err := s.ItemRepository.GetItemById(int(itemId))
+		if err != nil || item.ID == 0 {
+			log.Printf("Could not load item %d of swap %d: %v\n", itemId, swap.ID, err)
+			continue
+		}
+		items = append(items, *item)
+	}
+	return items
 }
 
 
-func (s *swapService) CompleteSwapRequest(ownerId uint, amount float64, swapId uint) (string, error) {
-	return s.SwapRepository.CompleteSwapRequest(ownerId, amount, swapId)
+// itemsUpdated passes each item before and after the swap to the observers
+func (s *swapService) itemsUpdated(before []models.Item) {
+	for _, item := range before {
+		after, err := s.ItemRepository.GetItemById(int(item.ID))
+		if err != nil {
+			log.Printf("Could not reload item %d after swap: %v\n", item.ID, err)
+			continue
+		}
+
+		for _, observer := range s.Observers {
+			go observer.ItemUpdated(item, *after)
+		}
+	}
 }
 
 
@@ -46,6 +128,6 @@ func (s *swapService) GetIncompleteSwapByInitiatorId(initiatorId, itemId int) (m
 }
 
 
-func (s *swapService) GetAllIncompleteSwapByOwnerId(ownerId int, limit, page int) ([]models.IncompleteSwaps, error) {
-	return s.SwapRepository.GetAllIncompleteSwapByOwnerId(ownerId, limit, page)
-}
\ No newline at end of file
+func (s *swapService) GetAllIncompleteSwapByOwnerId(ownerId int, page models.PageRequest) ([]models.IncompleteSwaps, *models.Page, error) {
+	return s.SwapRepository.GetAllIncompleteSwapByOwnerId(ownerId, page)
+}
@@ -0,0 +1,164 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strings"
+
+	"swap/apperrors"
+	"swap/models"
+	"swap/utils"
+)
+
+
+// thumbnailQueueSize bounds how many uploads can wait to be resized. Images
+// that do not fit are picked up by GenerateMissing on the next start.
+const thumbnailQueueSize = 256
+
+
+const thumbnailBatchSize = 100
+
+
+type thumbnailService struct {
+	ImageRepository models.IImageRepository
+	BlobStore       models.IBlobStore
+	queue           chan models.Image
+}
+
+
+// NewThumbnailService starts workers that resize queued images in the background
+func NewThumbnailService(imageRepository models.IImageRepository, blobStore models.IBlobStore, workers int) models.IThumbnailService {
+	s := &thumbnailService{
+		ImageRepository: imageRepository,
+		BlobStore:       blobStore,
+		queue:           make(chan models.Image, thumbnailQueueSize),
+	}
+
+	for i := 0; i < workers; i++ {
+		go s.work()
+	}
+	return s
+}
+
+
+func (s *thumbnailService) Enqueue(image models.Image) {
+	select {
+	case s.queue <- image:
+	default:
+		log.Printf("Thumbnail queue is full, image %d will be resized later\n", image.ID)
+	}
+}
+
+
+func (s *thumbnailService) GenerateMissing() error {
+	var afterId uint
+	for {
+		images, err := s.ImageRepository.GetImagesWithoutVariants(afterId, thumbnailBatchSize)
+		if err != nil {
+			return err
+		}
+
+		for _, image := range images {
+			s.generate(image)
+			afterId = image.ID
+		}
+
+		if len(images) < thumbnailBatchSize {
+			return nil
+		}
+	}
+}
+
+
+func (s *thumbnailService) work() {
+	for image := range s.queue {
+		s.generate(image)
+	}
+}
+
+
+// generate stores every variant of an image. Files that cannot be decoded are
+// marked as done too, since they will only ever be served as they are.
+func (s *thumbnailService) generate(image models.Image) {
+	// Images deleted while they waited in the queue are not worth resizing
+	if _, err := s.ImageRepository.GetImage(int(image.ID)); err != nil {
+		if apperrors.Status(err) != http.StatusNotFound {
+			log.Printf("Could not check image %d before resizing it: %v\n", image.ID, err)
+		}
+		return
+	}
+
+	if !strings.HasPrefix(image.ContentType, "image/") {
+		s.markReady(image)
+		return
+	}
+
+	blob, _, err := s.BlobStore.Get(context.Background(), image.Key())
+	if err != nil {
+		log.Printf("Could not read image %d to resize it: %v\n", image.ID, err)
+		return
+	}
+	data, err := ioutil.ReadAll(blob)
+	blob.Close()
+	if err != nil {
+		log.Printf("Could not read image %d to resize it: %v\n", image.ID, err)
+		return
+	}
+
+	source, _, err := utils.DecodeImage(bytes.NewReader(data))
+	if err != nil {
+		log.Printf("Could not decode image %d, serving only the original: %v\n", image.ID, err)
+		s.markReady(image)
+		return
+	}
+
+	contentType := image.VariantContentType()
+	for size, bound := range models.ImageSizes {
+		buf := new(bytes.Buffer)
+		if err := utils.EncodeImage(buf, utils.Resize(source, bound), contentType); err != nil {
+			log.Printf("Could not encode %s variant of image %d: %v\n", size, image.ID, err)
+			return
+		}
+
+		if err := s.BlobStore.Put(context.Background(), image.VariantKey(size), buf, int64(buf.Len()), contentType); err != nil {
+			log.Printf("Could not store %s variant of image %d: %v\n", size, image.ID, err)
+			return
+		}
+	}
+
+	// An image deleted while it was being resized has already had its files
+	// removed, so the variants just stored would be left behind
+	if err := s.markReady(image); apperrors.Status(err) == http.StatusNotFound {
+		s.removeVariants(image)
+	}
+}
+
+
+func (s *thumbnailService) markReady(image models.Image) error {
+	err := s.ImageRepository.MarkVariantsReady(image.ID)
+	if err != nil && apperrors.Status(err) != http.StatusNotFound {
+		log.Printf("Could not record variants of image %d: %v\n", image.ID, err)
+	}
+	return err
+}
+
+
+// removeVariants deletes the variants of a deleted image, unless another
+// image still shares its files
+func (s *thumbnailService) removeVariants(image models.Image) {
+	shared, err := s.ImageRepository.CountImagesByFile(image.FilePath, image.FileName)
+	if err != nil || shared > 0 {
+		return
+	}
+
+	for size := range models.ImageSizes {
+		err := s.BlobStore.Delete(context.Background(), image.VariantKey(size))
+		if err != nil && !errors.Is(err, models.ErrBlobNotFound) {
+			log.Printf("Could not remove %s variant of deleted image %d: %v\n", size, image.ID, err)
+		}
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"swap/models"
 	"swap/utils"
 	"strconv"
+	"strings"
 
 	"github.com/sethvargo/go-password/password"
 )
@@ -220,8 +221,8 @@ func (s *userService) ConfirmPassword(userId uint, password string) (*models.Use
 }
 
 
-func (s *userService) Search(term string, limit, page int) ([]models.User, error) {
-	return s.UserRepository.Search(term, limit, page)
+func (s *userService) Search(term string, page models.PageRequest) ([]models.User, *models.Page, error) {
+	return s.UserRepository.Search(term, page)
 }
 
 
@@ -344,11 +345,48 @@ func (s *userService) EnableTOTP(userId int) error {
 }
 
 
-func (s *userService) GetUserTransactions(userId, limit, page int) ([]models.Transactions, error){
-	return s.UserRepository.GetUserTransactions(userId, limit, page)
+func (s *userService) GetUserTransactions(userId int, page models.PageRequest) ([]models.Transactions, *models.Page, error){
+	return s.UserRepository.GetUserTransactions(userId, page)
 }
 
 
 func (s *userService) GetUserByItemId(id int) (*models.User, error) {
 	return s.UserRepository.GetUserByItemId(id)
-}
\ No newline at end of file
+}
+
+
+func (s *userService) UpdateUserRole(userId int, role string) error {
+	if !models.IsValidRole(role) {
+		return apperrors.NewBadRequest("Unknown role: " + role)
+	}
+
+	return s.UserRepository.UpdateUserRole(uint(userId), role)
+}
+
+
+// BootstrapAdmins promotes the accounts with the given emails to admins.
+// It runs on startup so a fresh deployment always has someone able to manage it.
+func (s *userService) BootstrapAdmins(emails []string) {
+	for _, email := range emails {
+		email = strings.ToLower(strings.TrimSpace(email))
+		if email == "" {
+			continue
+		}
+
+		user, err := s.UserRepository.FindUserByEmail(email)
+		if err != nil {
+			log.Printf("Unable to bootstrap admin %s: %v\n", email, err)
+			continue
+		}
+
+		if user.Role == models.AdminRole {
+			continue
+		}
+
+		if err := s.UserRepository.UpdateUserRole(user.ID, models.AdminRole); err != nil {
+			log.Printf("Unable to bootstrap admin %s: %v\n", email, err)
+			continue
+		}
+		log.Printf("Promoted %s to admin\n", email)
+	}
+}
@@ -0,0 +1,89 @@
+package services
+
+import (
+	"fmt"
+	"log"
+
+	"swap/models"
+)
+
+
+type watchlistService struct {
+	WatchlistRepository models.IWatchlistRepository
+	NotificationService models.INotificationService
+}
+
+
+func NewWatchlistService(WatchlistRepository models.IWatchlistRepository, NotificationService models.INotificationService) models.IWatchlistService {
+	return &watchlistService{
+		WatchlistRepository: WatchlistRepository,
+		NotificationService: NotificationService,
+	}
+}
+
+
+func (s *watchlistService) WatchItem(userId, itemId uint) error {
+	return s.WatchlistRepository.WatchItem(userId, itemId)
+}
+
+
+func (s *watchlistService) UnwatchItem(userId, itemId uint) error {
+	return s.WatchlistRepository.UnwatchItem(userId, itemId)
+}
+
+
+func (s *watchlistService) GetWatchedItems(userId uint, page models.PageRequest) ([]models.Item, *models.Page, error) {
+	return s.WatchlistRepository.GetWatchedItems(userId, page)
+}
+
+
+// ItemRegistered does nothing: nobody can be watching a brand new item
+func (s *watchlistService) ItemRegistered(item models.Item) {}
+
+
+// ItemUpdated tells everyone watching the item when its price drops or it is sold or relisted
+func (s *watchlistService) ItemUpdated(before, after models.Item) {
+	title, body := watchlistChange(before, after)
+	if title == "" {
+		return
+	}
+
+	watcherIds, err := s.WatchlistRepository.GetWatcherIds(after.ID)
+	if err != nil {
+		log.Printf("Could not find watchers of item %d: %v\n", after.ID, err)
+		return
+	}
+
+	for _, watcherId := range watcherIds {
+		notification := &models.Notification{
+			UserId: watcherId,
+			Kind:   models.WatchlistNotification,
+			Title:  title,
+			Body:   body,
+			ItemId: &after.ID,
+		}
+		if err := s.NotificationService.Notify(notification, true); err != nil {
+			log.Printf("Could not notify user %d about item %d: %v\n", watcherId, after.ID, err)
+		}
+	}
+}
+
+
+// watchlistChange describes the change watchers care about, if any
+func watchlistChange(before, after models.Item) (string, string) {
+	switch {
+	case !before.Sold && after.Sold:
+		return fmt.Sprintf("%s has been sold", after.Name),
+			fmt.Sprintf("%s, an item on your watchlist, is no longer available", after.Name)
+	case !before.Expired && after.Expired:
+		return fmt.Sprintf("%s is no longer listed", after.Name),
+			fmt.Sprintf("%s, an item on your watchlist, is no longer available", after.Name)
+	case before.Sold && !after.Sold, before.Expired && !after.Expired:
+		return fmt.Sprintf("%s is available again", after.Name),
+			fmt.Sprintf("%s, an item on your watchlist, has been relisted for $%.2f", after.Name, after.Prize)
+	case after.Prize < before.Prize:
+		return fmt.Sprintf("Price drop on %s", after.Name),
+			fmt.Sprintf("%s, an item on your watchlist, dropped from $%.2f to $%.2f", after.Name, before.Prize, after.Prize)
+	}
+	return "", ""
+}
@@ -0,0 +1,246 @@
+package utils
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"swap/models"
+)
+
+
+// localBlobStore keeps blobs as files below a directory, each with a hidden
+// file next to it holding its content type. Its signed URLs point at this
+// application, which checks them with VerifySignedURL.
+type localBlobStore struct {
+	root     string
+	baseURL  string
+	secret   []byte
+}
+
+
+func NewLocalBlobStore(root, baseURL, secret string) (models.IBlobStore, error) {
+	if err := os.MkdirAll(root, os.ModePerm); err != nil {
+		return nil, fmt.Errorf("Failed to create blob directory: %v", err)
+	}
+	if secret == "" {
+		return nil, errors.New("A signing key is required for local blob URLs")
+	}
+
+	return &localBlobStore{
+		root:    root,
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		secret:  []byte(secret),
+	}, nil
+}
+
+
+func (s *localBlobStore) path(key string) (string, string, error) {
+	cleaned, err := cleanBlobKey(key)
+	if err != nil {
+		return "", "", err
+	}
+	return cleaned, filepath.Join(s.root, filepath.FromSlash(cleaned)), nil
+}
+
+
+// typePath is where the content type of the blob stored at filePath is kept
+func typePath(filePath string) string {
+	return filepath.Join(filepath.Dir(filePath), "."+filepath.Base(filePath)+".type")
+}
+
+
+// contentType reads a blob's stored content type. Blobs saved before types
+// were stored fall back to a guess from the key's extension.
+func contentType(key, filePath string) string {
+	if stored, err := os.ReadFile(typePath(filePath)); err == nil && len(stored) > 0 {
+		return string(stored)
+	}
+	if guessed := mime.TypeByExtension(path.Ext(key)); guessed != "" {
+		return guessed
+	}
+	return "application/octet-stream"
+}
+
+
+// Put writes to a temporary file first so readers never see half a blob
+func (s *localBlobStore) Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error {
+	_, filePath, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(filePath), os.ModePerm); err != nil {
+		return fmt.Errorf("Failed to create blob directory: %v", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(filePath), ".upload-*")
+	if err != nil {
+		return fmt.Errorf("Failed to create file: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, body); err != nil {
+		tmp.Close()
+		return fmt.Errorf("Failed to save file: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("Failed to save file: %v", err)
+	}
+
+	// The type goes first so the blob never appears without it
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	if err := os.WriteFile(typePath(filePath), []byte(contentType), 0644); err != nil {
+		return fmt.Errorf("Failed to save file: %v", err)
+	}
+	return os.Rename(tmp.Name(), filePath)
+}
+
+
+func (s *localBlobStore) Get(ctx context.Context, key string) (io.ReadCloser, *models.BlobInfo, error) {
+	cleaned, filePath, err := s.path(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, nil, localBlobError(err)
+	}
+
+	stat, err := file.Stat()
+	if err != nil || stat.IsDir() {
+		file.Close()
+		return nil, nil, models.ErrBlobNotFound
+	}
+	return file, localBlobInfo(cleaned, stat, contentType(cleaned, filePath)), nil
+}
+
+
+func (s *localBlobStore) Delete(ctx context.Context, key string) error {
+	_, filePath, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(filePath); err != nil {
+		return localBlobError(err)
+	}
+	if err := os.Remove(typePath(filePath)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+
+func (s *localBlobStore) Stat(ctx context.Context, key string) (*models.BlobInfo, error) {
+	cleaned, filePath, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	stat, err := os.Stat(filePath)
+	if err != nil {
+		return nil, localBlobError(err)
+	}
+	if stat.IsDir() {
+		return nil, models.ErrBlobNotFound
+	}
+	return localBlobInfo(cleaned, stat, contentType(cleaned, filePath)), nil
+}
+
+
+func (s *localBlobStore) List(ctx context.Context, prefix string) ([]models.BlobInfo, error) {
+	var blobs []models.BlobInfo
+
+	err := filepath.WalkDir(s.root, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		// Hidden files are the store's own: uploads in progress and content types
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			return nil
+		}
+
+		relative, err := filepath.Rel(s.root, filePath)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(relative)
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+
+		stat, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		blobs = append(blobs, *localBlobInfo(key, stat, contentType(key, filePath)))
+		return ctx.Err()
+	})
+	return blobs, err
+}
+
+
+func (s *localBlobStore) SignedURL(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	cleaned, err := cleanBlobKey(key)
+	if err != nil {
+		return "", err
+	}
+
+	expires := time.Now().Add(expiry).Unix()
+	query := url.Values{}
+	query.Set("expires", strconv.FormatInt(expires, 10))
+	query.Set("signature", s.sign(cleaned, expires))
+
+	return s.baseURL + "/" + (&url.URL{Path: cleaned}).EscapedPath() + "?" + query.Encode(), nil
+}
+
+
+func (s *localBlobStore) VerifySignedURL(key string, expires int64, signature string) bool {
+	cleaned, err := cleanBlobKey(key)
+	if err != nil || time.Now().Unix() > expires {
+		return false
+	}
+	return hmac.Equal([]byte(signature), []byte(s.sign(cleaned, expires)))
+}
+
+
+func (s *localBlobStore) sign(key string, expires int64) string {
+	mac := hmac.New(sha256.New, s.secret)
+	fmt.Fprintf(mac, "%s\n%d", key, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+
+func localBlobInfo(key string, stat fs.FileInfo, contentType string) *models.BlobInfo {
+	return &models.BlobInfo{
+		Key:         key,
+		Size:        stat.Size(),
+		ContentType: contentType,
+		ModTime:     stat.ModTime(),
+	}
+}
+
+
+func localBlobError(err error) error {
+	if errors.Is(err, fs.ErrNotExist) {
+		return models.ErrBlobNotFound
+	}
+	return err
+}
@@ -0,0 +1,214 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"swap/models"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+
+// s3MaxSignedURL is the longest a presigned S3 URL can stay valid
+const s3MaxSignedURL = 7 * 24 * time.Hour
+
+
+// S3Config locates a bucket on AWS S3 or any service speaking its API, such as
+// MinIO. Endpoint defaults to AWS in Region; most self-hosted services need
+// PathStyle. Without keys the SDK's default credential chain is used.
+type S3Config struct {
+	Endpoint         string
+	Region           string
+	Bucket           string
+	AccessKeyId      string
+	SecretAccessKey  string
+	PathStyle        bool
+}
+
+
+type s3BlobStore struct {
+	bucket     string
+	client     *s3.Client
+	uploader   *manager.Uploader
+	presigner  *s3.PresignClient
+}
+
+
+func NewS3BlobStore(config S3Config) (models.IBlobStore, error) {
+	if config.Bucket == "" {
+		return nil, errors.New("S3_BUCKET must be set")
+	}
+	if config.Region == "" {
+		config.Region = "us-east-1"
+	}
+
+	options := []func(*awsconfig.LoadOptions) error{awsconfig.WithRegion(config.Region)}
+	if config.AccessKeyId != "" || config.SecretAccessKey != "" {
+		provider := credentials.NewStaticCredentialsProvider(config.AccessKeyId, config.SecretAccessKey, "")
+		options = append(options, awsconfig.WithCredentialsProvider(provider))
+	}
+
+	awsConfig, err := awsconfig.LoadDefaultConfig(context.Background(), options...)
+	if err != nil {
+		return nil, fmt.Errorf("Could not load S3 configuration: %v", err)
+	}
+
+	client := s3.NewFromConfig(awsConfig, func(o *s3.Options) {
+		o.UsePathStyle = config.PathStyle
+		if config.Endpoint != "" {
+			o.EndpointResolver = s3.EndpointResolverFromURL(config.Endpoint)
+		}
+	})
+
+	return &s3BlobStore{
+		bucket:    config.Bucket,
+		client:    client,
+		uploader:  manager.NewUploader(client),
+		presigner: s3.NewPresignClient(client),
+	}, nil
+}
+
+
+// Put hands the body to the upload manager, which switches to a multipart
+// upload for large or unsized bodies
+func (s *s3BlobStore) Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error {
+	key, err := cleanBlobKey(key)
+	if err != nil {
+		return err
+	}
+
+	input := &s3.PutObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+		Body:   body,
+	}
+	if contentType != "" {
+		input.ContentType = aws.String(contentType)
+	}
+
+	if _, err := s.uploader.Upload(ctx, input); err != nil {
+		return fmt.Errorf("Failed to store blob %s: %v", key, err)
+	}
+	return nil
+}
+
+
+func (s *s3BlobStore) Get(ctx context.Context, key string) (io.ReadCloser, *models.BlobInfo, error) {
+	key, err := cleanBlobKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	output, err := s.client.GetObject(ctx, &s3.GetObjectInput{Bucket: aws.String(s.bucket), Key: aws.String(key)})
+	if err != nil {
+		return nil, nil, s3Error(key, err)
+	}
+
+	info := &models.BlobInfo{
+		Key:         key,
+		Size:        output.ContentLength,
+		ContentType: aws.ToString(output.ContentType),
+		ModTime:     aws.ToTime(output.LastModified),
+	}
+	return output.Body, info, nil
+}
+
+
+func (s *s3BlobStore) Delete(ctx context.Context, key string) error {
+	key, err := cleanBlobKey(key)
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.client.DeleteObject(ctx, &s3.DeleteObjectInput{Bucket: aws.String(s.bucket), Key: aws.String(key)}); err != nil {
+		return s3Error(key, err)
+	}
+	return nil
+}
+
+
+func (s *s3BlobStore) Stat(ctx context.Context, key string) (*models.BlobInfo, error) {
+	key, err := cleanBlobKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := s.client.HeadObject(ctx, &s3.HeadObjectInput{Bucket: aws.String(s.bucket), Key: aws.String(key)})
+	if err != nil {
+		return nil, s3Error(key, err)
+	}
+
+	return &models.BlobInfo{
+		Key:         key,
+		Size:        output.ContentLength,
+		ContentType: aws.ToString(output.ContentType),
+		ModTime:     aws.ToTime(output.LastModified),
+	}, nil
+}
+
+
+// List pages through ListObjectsV2 until every key under prefix has been seen
+func (s *s3BlobStore) List(ctx context.Context, prefix string) ([]models.BlobInfo, error) {
+	var blobs []models.BlobInfo
+
+	paginator := s3.NewListObjectsV2Paginator(s.client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(prefix),
+	})
+
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to list blobs under %q: %v", prefix, err)
+		}
+
+		for _, object := range page.Contents {
+			blobs = append(blobs, models.BlobInfo{
+				Key:     aws.ToString(object.Key),
+				Size:    object.Size,
+				ModTime: aws.ToTime(object.LastModified),
+			})
+		}
+	}
+	return blobs, nil
+}
+
+
+func (s *s3BlobStore) SignedURL(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	if expiry <= 0 || expiry > s3MaxSignedURL {
+		return "", fmt.Errorf("Signed URLs must expire within %v", s3MaxSignedURL)
+	}
+
+	key, err := cleanBlobKey(key)
+	if err != nil {
+		return "", err
+	}
+
+	request, err := s.presigner.PresignGetObject(ctx, &s3.GetObjectInput{Bucket: aws.String(s.bucket), Key: aws.String(key)},
+		s3.WithPresignExpires(expiry))
+	if err != nil {
+		return "", fmt.Errorf("Failed to sign URL for %s: %v", key, err)
+	}
+	return request.URL, nil
+}
+
+
+// s3Error turns a missing object into models.ErrBlobNotFound. HEAD responses
+// carry no error code, so the status is checked rather than the error type.
+func s3Error(key string, err error) error {
+	var responseError *awshttp.ResponseError
+	if errors.As(err, &responseError) && responseError.HTTPStatusCode() == http.StatusNotFound {
+		return models.ErrBlobNotFound
+	}
+	return fmt.Errorf("S3 request for %s failed: %v", key, err)
+}
@@ -0,0 +1,60 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"swap/models"
+)
+
+
+// NewBlobStore returns the blob store selected by driver: "local" (the
+// default) keeps files on disk, "s3" in any S3-compatible bucket. Both are
+// configured from the environment.
+func NewBlobStore(driver string) (models.IBlobStore, error) {
+	switch strings.ToLower(driver) {
+	case "", "local":
+		dir := os.Getenv("BLOB_DIR")
+		if dir == "" {
+			dir = "./uploads"
+		}
+		baseURL := os.Getenv("BLOB_BASE_URL")
+		if baseURL == "" {
+			baseURL = "/blobs"
+		}
+		secret := os.Getenv("BLOB_SIGNING_KEY")
+		if secret == "" {
+			secret = os.Getenv("SECRET")
+		}
+		return NewLocalBlobStore(dir, baseURL, secret)
+	case "s3":
+		return NewS3BlobStore(S3Config{
+			Endpoint:        os.Getenv("S3_ENDPOINT"),
+			Region:          os.Getenv("S3_REGION"),
+			Bucket:          os.Getenv("S3_BUCKET"),
+			AccessKeyId:     os.Getenv("S3_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("S3_SECRET_ACCESS_KEY"),
+			PathStyle:       os.Getenv("S3_PATH_STYLE") == "true",
+		})
+	}
+	return nil, fmt.Errorf("Unknown blob store driver %q", driver)
+}
+
+
+// cleanBlobKey normalises a key and rejects those that would climb out of the
+// store, such as "../secrets"
+func cleanBlobKey(key string) (string, error) {
+	slashed := strings.ReplaceAll(key, "\\", "/")
+	cleaned := strings.TrimPrefix(path.Clean("/"+slashed), "/")
+	if cleaned == "" || strings.Contains(key, "\x00") {
+		return "", fmt.Errorf("Invalid blob key %q", key)
+	}
+	for _, segment := range strings.Split(slashed, "/") {
+		if segment == ".." {
+			return "", fmt.Errorf("Invalid blob key %q", key)
+		}
+	}
+	return cleaned, nil
+}
@@ -0,0 +1,285 @@
+package utils_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"swap/handler"
+	"swap/models"
+	"swap/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+
+// fakeS3 is a MinIO-style stand-in for one path-style bucket, keeping objects
+// in memory. Listings return two keys a page so paging is exercised.
+type fakeS3 struct {
+	bucket  string
+	mu      sync.Mutex
+	objects map[string]fakeObject
+}
+
+
+type fakeObject struct {
+	data        []byte
+	contentType string
+	modTime     time.Time
+}
+
+
+type fakeListResult struct {
+	XMLName               xml.Name `xml:"ListBucketResult"`
+	Contents              []fakeListEntry
+	IsTruncated           bool
+	NextContinuationToken string `xml:",omitempty"`
+	KeyCount              int
+}
+
+
+type fakeListEntry struct {
+	Key          string
+	Size         int64
+	LastModified string
+}
+
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	presigned := query.Get("X-Amz-Signature") != "" && query.Get("X-Amz-Expires") != ""
+	if !presigned && !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 ") {
+		http.Error(w, "unsigned request", http.StatusForbidden)
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/")
+	if path != f.bucket && !strings.HasPrefix(path, f.bucket+"/") {
+		http.Error(w, "no such bucket", http.StatusNotFound)
+		return
+	}
+	key := strings.TrimPrefix(strings.TrimPrefix(path, f.bucket), "/")
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if key == "" && r.Method == http.MethodGet {
+		f.list(w, query.Get("prefix"), query.Get("continuation-token"))
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		data, _ := ioutil.ReadAll(r.Body)
+		f.objects[key] = fakeObject{data: data, contentType: r.Header.Get("Content-Type"), modTime: time.Now().UTC()}
+		w.Header().Set("ETag", `"etag"`)
+	case http.MethodGet, http.MethodHead:
+		object, ok := f.objects[key]
+		if !ok {
+			w.Header().Set("Content-Type", "application/xml")
+			w.WriteHeader(http.StatusNotFound)
+			if r.Method == http.MethodGet {
+				w.Write([]byte(`<Error><Code>NoSuchKey</Code><Message>missing</Message></Error>`))
+			}
+			return
+		}
+		w.Header().Set("Content-Type", object.contentType)
+		w.Header().Set("Content-Length", strconv.Itoa(len(object.data)))
+		w.Header().Set("Last-Modified", object.modTime.Format(http.TimeFormat))
+		if r.Method == http.MethodGet {
+			w.Write(object.data)
+		}
+	case http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "unsupported", http.StatusMethodNotAllowed)
+	}
+}
+
+
+func (f *fakeS3) list(w http.ResponseWriter, prefix, token string) {
+	var keys []string
+	for key := range f.objects {
+		if strings.HasPrefix(key, prefix) && key > token {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	result := fakeListResult{}
+	if len(keys) > 2 {
+		keys = keys[:2]
+		result.IsTruncated = true
+		result.NextContinuationToken = keys[1]
+	}
+	for _, key := range keys {
+		object := f.objects[key]
+		result.Contents = append(result.Contents, fakeListEntry{
+			Key:          key,
+			Size:         int64(len(object.data)),
+			LastModified: object.modTime.Format(time.RFC3339),
+		})
+	}
+	result.KeyCount = len(result.Contents)
+
+	w.Header().Set("Content-Type", "application/xml")
+	xml.NewEncoder(w).Encode(result)
+}
+
+
+func newS3Store(t *testing.T) models.IBlobStore {
+	fake := &fakeS3{bucket: "swap", objects: map[string]fakeObject{}}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	store, err := utils.NewS3BlobStore(utils.S3Config{
+		Endpoint:        server.URL,
+		Region:          "us-east-1",
+		Bucket:          "swap",
+		AccessKeyId:     "minio",
+		SecretAccessKey: "minio-secret",
+		PathStyle:       true,
+	})
+	if err != nil {
+		t.Fatalf("NewS3BlobStore: %v", err)
+	}
+	return store
+}
+
+
+// newLocalStore serves the disk store's signed URLs through BlobHandler, as main does
+func newLocalStore(t *testing.T) models.IBlobStore {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	server := httptest.NewServer(engine)
+	t.Cleanup(server.Close)
+
+	store, err := utils.NewLocalBlobStore(t.TempDir(), server.URL+"/blobs", "test-secret")
+	if err != nil {
+		t.Fatalf("NewLocalBlobStore: %v", err)
+	}
+
+	blobHandler := handler.NewBlobHandler(store, store.(models.IBlobURLVerifier))
+	engine.GET("/blobs/*key", blobHandler.ServeSignedBlob)
+	return store
+}
+
+
+func TestBlobStores(t *testing.T) {
+	drivers := map[string]func(*testing.T) models.IBlobStore{
+		"local": newLocalStore,
+		"s3":    newS3Store,
+	}
+
+	for name, newStore := range drivers {
+		t.Run(name, func(t *testing.T) {
+			testBlobStore(t, newStore(t))
+		})
+	}
+}
+
+
+func testBlobStore(t *testing.T, store models.IBlobStore) {
+	ctx := context.Background()
+	content := []byte("\x89PNG not really")
+
+	for _, key := range []string{"item_1/a.png", "item_1/b.png", "item_1/c.png", "item_2/d.png"} {
+		if err := store.Put(ctx, key, bytes.NewReader(content), int64(len(content)), "image/png"); err != nil {
+			t.Fatalf("Put %s: %v", key, err)
+		}
+	}
+
+	// Bodies of unknown size must be accepted too
+	if err := store.Put(ctx, "item_3/unsized.txt", strings.NewReader("unsized"), -1, "text/plain"); err != nil {
+		t.Fatalf("Put with unknown size: %v", err)
+	}
+
+	blob, info, err := store.Get(ctx, "item_1/a.png")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	data, _ := ioutil.ReadAll(blob)
+	blob.Close()
+	if !bytes.Equal(data, content) || info.Size != int64(len(content)) || info.ContentType != "image/png" {
+		t.Errorf("Get returned %q with %+v", data, info)
+	}
+
+	// The type given to Put wins over whatever the key's extension suggests
+	if err := store.Put(ctx, "item_4/mislabelled.html", bytes.NewReader(content), int64(len(content)), "image/png"); err != nil {
+		t.Fatalf("Put %s: %v", "item_4/mislabelled.html", err)
+	}
+	for _, check := range []func() (*models.BlobInfo, error){
+		func() (*models.BlobInfo, error) { return store.Stat(ctx, "item_4/mislabelled.html") },
+		func() (*models.BlobInfo, error) {
+			blob, info, err := store.Get(ctx, "item_4/mislabelled.html")
+			if err == nil {
+				blob.Close()
+			}
+			return info, err
+		},
+	} {
+		if info, err := check(); err != nil || info.ContentType != "image/png" {
+			t.Errorf("mislabelled blob reported %+v, %v; want image/png", info, err)
+		}
+	}
+
+	stat, err := store.Stat(ctx, "item_1/b.png")
+	if err != nil || stat.Size != int64(len(content)) || stat.ModTime.IsZero() {
+		t.Errorf("Stat = %+v, %v", stat, err)
+	}
+
+	if _, _, err := store.Get(ctx, "item_1/missing.png"); !errors.Is(err, models.ErrBlobNotFound) {
+		t.Errorf("Get of a missing key returned %v, want ErrBlobNotFound", err)
+	}
+	if _, err := store.Stat(ctx, "item_1/missing.png"); !errors.Is(err, models.ErrBlobNotFound) {
+		t.Errorf("Stat of a missing key returned %v, want ErrBlobNotFound", err)
+	}
+	if err := store.Put(ctx, "../escape.png", bytes.NewReader(content), int64(len(content)), "image/png"); err == nil {
+		t.Error("Put accepted a key outside the store")
+	}
+
+	blobs, err := store.List(ctx, "item_1/")
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	var keys []string
+	for _, blob := range blobs {
+		keys = append(keys, blob.Key)
+	}
+	sort.Strings(keys)
+	if strings.Join(keys, ",") != "item_1/a.png,item_1/b.png,item_1/c.png" {
+		t.Errorf("List returned %v", keys)
+	}
+
+	url, err := store.SignedURL(ctx, "item_1/c.png", time.Minute)
+	if err != nil {
+		t.Fatalf("SignedURL: %v", err)
+	}
+	response, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("fetching signed URL: %v", err)
+	}
+	data, _ = ioutil.ReadAll(response.Body)
+	response.Body.Close()
+	if response.StatusCode != http.StatusOK || !bytes.Equal(data, content) {
+		t.Errorf("signed URL %s returned %d %q", url, response.StatusCode, data)
+	}
+
+	if err := store.Delete(ctx, "item_1/a.png"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := store.Stat(ctx, "item_1/a.png"); !errors.Is(err, models.ErrBlobNotFound) {
+		t.Errorf("Stat after Delete returned %v, want ErrBlobNotFound", err)
+	}
+}
@@ -0,0 +1,162 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"swap/apperrors"
+	"swap/models"
+)
+
+
+const (
+	StubGeocoder      = "stub"
+	NominatimGeocoder = "nominatim"
+
+	defaultNominatimURL = "https://nominatim.openstreetmap.org/search"
+)
+
+
+// NewGeocoder returns the geocoding provider with the given name.
+// Unknown or empty names fall back to the offline stub.
+func NewGeocoder(provider string) models.IGeocoder {
+	switch strings.ToLower(strings.TrimSpace(provider)) {
+	case NominatimGeocoder:
+		baseURL := os.Getenv("NOMINATIM_URL")
+		if baseURL == "" {
+			baseURL = defaultNominatimURL
+		}
+		return &nominatimGeocoder{
+			baseURL: baseURL,
+			client:  &http.Client{Timeout: 5 * time.Second},
+		}
+	default:
+		return &stubGeocoder{}
+	}
+}
+
+
+// stubGeocoder resolves addresses without any network access. It understands
+// literal "latitude,longitude" pairs and a small table of well known cities,
+// which is enough for development and tests.
+type stubGeocoder struct{}
+
+
+var stubCities = map[string]models.Coordinates{
+	"ABUJA":         {Latitude: 9.0765, Longitude: 7.3986},
+	"ACCRA":         {Latitude: 5.6037, Longitude: -0.1870},
+	"BERLIN":        {Latitude: 52.5200, Longitude: 13.4050},
+	"CAIRO":         {Latitude: 30.0444, Longitude: 31.2357},
+	"IBADAN":        {Latitude: 7.3775, Longitude: 3.9470},
+	"JOHANNESBURG":  {Latitude: -26.2041, Longitude: 28.0473},
+	"LAGOS":         {Latitude: 6.5244, Longitude: 3.3792},
+	"LONDON":        {Latitude: 51.5074, Longitude: -0.1278},
+	"NAIROBI":       {Latitude: -1.2921, Longitude: 36.8219},
+	"NEW YORK":      {Latitude: 40.7128, Longitude: -74.0060},
+	"PARIS":         {Latitude: 48.8566, Longitude: 2.3522},
+	"PORT HARCOURT": {Latitude: 4.8156, Longitude: 7.0498},
+	"SAN FRANCISCO": {Latitude: 37.7749, Longitude: -122.4194},
+	"TORONTO":       {Latitude: 43.6532, Longitude: -79.3832},
+}
+
+
+func (g *stubGeocoder) Geocode(address string) (*models.Coordinates, error) {
+	if coordinates, ok := parseCoordinates(address); ok {
+		return coordinates, nil
+	}
+
+	// Match on the first part of addresses like "Lagos, Nigeria"
+	city := strings.ToUpper(strings.TrimSpace(strings.Split(address, ",")[0]))
+	if coordinates, ok := stubCities[city]; ok {
+		return &coordinates, nil
+	}
+	return nil, apperrors.NewNotFound("location", address)
+}
+
+
+// nominatimGeocoder looks addresses up with an OpenStreetMap Nominatim server
+type nominatimGeocoder struct {
+	baseURL string
+	client  *http.Client
+}
+
+
+func (g *nominatimGeocoder) Geocode(address string) (*models.Coordinates, error) {
+	if coordinates, ok := parseCoordinates(address); ok {
+		return coordinates, nil
+	}
+
+	query := url.Values{}
+	query.Set("q", address)
+	query.Set("format", "json")
+	query.Set("limit", "1")
+
+	request, err := http.NewRequest(http.MethodGet, g.baseURL+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, apperrors.NewInternal()
+	}
+	// Nominatim's usage policy requires an identifying user agent
+	request.Header.Set("User-Agent", Swap)
+
+	response, err := g.client.Do(request)
+	if err != nil {
+		log.Printf("Geocoding request failed: %v\n", err)
+		return nil, apperrors.NewServiceUnavailable()
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		log.Printf("Geocoding request failed with status %d\n", response.StatusCode)
+		return nil, apperrors.NewServiceUnavailable()
+	}
+
+	var places []struct {
+		Lat string `json:"lat"`
+		Lon string `json:"lon"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&places); err != nil {
+		log.Printf("Could not decode geocoding response: %v\n", err)
+		return nil, apperrors.NewServiceUnavailable()
+	}
+
+	if len(places) == 0 {
+		return nil, apperrors.NewNotFound("location", address)
+	}
+
+	coordinates, ok := parseCoordinates(fmt.Sprintf("%s,%s", places[0].Lat, places[0].Lon))
+	if !ok {
+		return nil, apperrors.NewServiceUnavailable()
+	}
+	return coordinates, nil
+}
+
+
+// parseCoordinates reads a "latitude,longitude" pair such as "6.5244,3.3792"
+func parseCoordinates(value string) (*models.Coordinates, bool) {
+	parts := strings.Split(value, ",")
+	if len(parts) != 2 {
+		return nil, false
+	}
+
+	latitude, err := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+	if err != nil {
+		return nil, false
+	}
+	longitude, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+	if err != nil {
+		return nil, false
+	}
+
+	coordinates := &models.Coordinates{Latitude: latitude, Longitude: longitude}
+	if !coordinates.Valid() {
+		return nil, false
+	}
+	return coordinates, true
+}
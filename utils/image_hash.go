@@ -0,0 +1,71 @@
+package utils
+
+import (
+	"image"
+	"math/bits"
+)
+
+
+// DifferenceHash computes a 64-bit perceptual hash of an image: it is shrunk
+// to 9x8 grey cells and each bit records whether a cell is brighter than its
+// right-hand neighbour. Resized or recompressed copies hash to nearly the same value.
+func DifferenceHash(img image.Image) uint64 {
+	var cells [8][9]float64
+	bounds := img.Bounds()
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 9; x++ {
+			cells[y][x] = cellBrightness(img, bounds, x, y)
+		}
+	}
+
+	var hash uint64
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			hash <<= 1
+			if cells[y][x] > cells[y][x+1] {
+				hash |= 1
+			}
+		}
+	}
+	return hash
+}
+
+
+// HammingDistance counts the bits two hashes differ in
+func HammingDistance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+
+// cellBrightness averages the luminance of one cell of a 9x8 grid laid over
+// the image, sampling at most 16x16 pixels per cell
+func cellBrightness(img image.Image, bounds image.Rectangle, x, y int) float64 {
+	x0 := bounds.Min.X + x*bounds.Dx()/9
+	x1 := bounds.Min.X + (x+1)*bounds.Dx()/9
+	y0 := bounds.Min.Y + y*bounds.Dy()/8
+	y1 := bounds.Min.Y + (y+1)*bounds.Dy()/8
+	if x1 <= x0 {
+		x1 = x0 + 1
+	}
+	if y1 <= y0 {
+		y1 = y0 + 1
+	}
+
+	stepX := (x1-x0)/16 + 1
+	stepY := (y1-y0)/16 + 1
+
+	var total float64
+	var samples int
+	for py := y0; py < y1 && py < bounds.Max.Y; py += stepY {
+		for px := x0; px < x1 && px < bounds.Max.X; px += stepX {
+			r, g, b, _ := img.At(px, py).RGBA()
+			total += 0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)
+			samples++
+		}
+	}
+
+	if samples == 0 {
+		return 0
+	}
+	return total / float64(samples)
+}
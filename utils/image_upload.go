@@ -0,0 +1,191 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/draw"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+
+	"swap/apperrors"
+	"swap/models"
+)
+
+
+// ImageUpload is an uploaded image that has been checked and re-encoded
+type ImageUpload struct {
+	Data        []byte
+	ContentType string
+	Image       image.Image // Decoded and turned upright, for hashing
+}
+
+
+// ReadImageUpload checks an uploaded file against the size limit and the
+// allowed formats, judged by its first bytes rather than its name or claimed
+// type, and re-encodes it. Re-encoding drops EXIF and any other metadata, such
+// as the GPS position of a photo, after applying the photo's orientation.
+func ReadImageUpload(file *multipart.FileHeader) (*ImageUpload, error) {
+	if file.Size > models.MaxImageUploadSize {
+		return nil, apperrors.NewPayloadTooLarge(models.MaxImageUploadSize, file.Size)
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return nil, apperrors.NewBadRequest("Could not read uploaded file")
+	}
+	defer src.Close()
+
+	data, err := ioutil.ReadAll(io.LimitReader(src, models.MaxImageUploadSize+1))
+	if err != nil {
+		return nil, apperrors.NewBadRequest("Could not read uploaded file")
+	}
+	if int64(len(data)) > models.MaxImageUploadSize {
+		return nil, apperrors.NewPayloadTooLarge(models.MaxImageUploadSize, int64(len(data)))
+	}
+
+	contentType := http.DetectContentType(data)
+	if !models.IsAllowedImageType(contentType) {
+		return nil, apperrors.NewUnsupportedMediaType(fmt.Sprintf("Files of type %s cannot be uploaded; use JPEG, PNG or GIF", contentType))
+	}
+
+	// The header is checked before decoding so a small file claiming huge
+	// dimensions is never inflated into memory
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, apperrors.NewBadRequest("Uploaded image could not be read")
+	}
+	if config.Width*config.Height > MaxImagePixels {
+		return nil, apperrors.NewBadRequest(fmt.Sprintf("Image of %dx%d is too large; images can have at most %d pixels",
+			config.Width, config.Height, MaxImagePixels))
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, apperrors.NewBadRequest("Uploaded image could not be read")
+	}
+
+	if contentType == "image/jpeg" {
+		img = orient(img, jpegOrientation(data))
+	}
+
+	buf := new(bytes.Buffer)
+	if err := EncodeImage(buf, img, contentType); err != nil {
+		return nil, fmt.Errorf("Failed to encode image: %v", err)
+	}
+	return &ImageUpload{Data: buf.Bytes(), ContentType: contentType, Image: img}, nil
+}
+
+
+// jpegOrientation reads the EXIF orientation of a JPEG, from 1 to 8, returning
+// 1 when it has none
+func jpegOrientation(data []byte) int {
+	if len(data) < 2 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+
+	for offset := 2; offset+4 <= len(data); {
+		if data[offset] != 0xFF {
+			return 1
+		}
+		marker := data[offset+1]
+		length := int(binary.BigEndian.Uint16(data[offset+2:]))
+		// Image data starts at SOS, after every metadata segment
+		if marker == 0xDA || length < 2 || offset+2+length > len(data) {
+			return 1
+		}
+
+		segment := data[offset+4 : offset+2+length]
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return exifOrientation(segment[6:])
+		}
+		offset += 2 + length
+	}
+	return 1
+}
+
+
+// exifOrientation finds the orientation tag in the first IFD of a TIFF block
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd < 0 || ifd+2 > len(tiff) {
+		return 1
+	}
+
+	entries := int(order.Uint16(tiff[ifd:]))
+	for i := 0; i < entries; i++ {
+		entry := ifd + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:]) == 0x0112 {
+			orientation := int(order.Uint16(tiff[entry+8:]))
+			if orientation < 1 || orientation > 8 {
+				return 1
+			}
+			return orientation
+		}
+	}
+	return 1
+}
+
+
+// orient turns and flips an image the way its EXIF orientation says it
+// should be displayed, since the tag is lost when the image is re-encoded
+func orient(src image.Image, orientation int) image.Image {
+	if orientation <= 1 {
+		return src
+	}
+
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	rgba := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(rgba, rgba.Bounds(), src, bounds.Min, draw.Src)
+
+	dstWidth, dstHeight := width, height
+	if orientation >= 5 {
+		dstWidth, dstHeight = height, width
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dstWidth, dstHeight))
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			dx, dy := x, y
+			switch orientation {
+			case 2:
+				dx = width - 1 - x
+			case 3:
+				dx, dy = width-1-x, height-1-y
+			case 4:
+				dy = height - 1 - y
+			case 5:
+				dx, dy = y, x
+			case 6:
+				dx, dy = height-1-y, x
+			case 7:
+				dx, dy = height-1-y, width-1-x
+			case 8:
+				dx, dy = y, width-1-x
+			}
+			copy(dst.Pix[dy*dst.Stride+dx*4:dy*dst.Stride+dx*4+4], rgba.Pix[y*rgba.Stride+x*4:y*rgba.Stride+x*4+4])
+		}
+	}
+	return dst
+}
@@ -1,156 +1,126 @@
 package utils
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"log"
 	"mime/multipart"
-	"os"
+	"path"
 	"path/filepath"
 	"time"
-	"log"
-	"swap/models"
 
+	"swap/apperrors"
+	"swap/models"
 
-	"io/ioutil"
+	"github.com/gofrs/uuid"
 )
 
 type Utils struct {
 	imageRepository models.IImageRepository
+	blobStore       models.IBlobStore
 }
 
 
-func NewUtils(imageRepo models.IImageRepository) *Utils {
+func NewUtils(imageRepo models.IImageRepository, blobStore models.IBlobStore) *Utils {
 	return &Utils{
 		imageRepository : imageRepo,
+		blobStore : blobStore,
 	}
 }
 
 
-func UploadFile(file *multipart.FileHeader, uploadDir string) (string, error) {
-	err := os.MkdirAll(uploadDir, os.ModePerm)
-
+// UploadFile checks and stores an uploaded image under folder and returns its blob key
+func UploadFile(store models.IBlobStore, file *multipart.FileHeader, folder string) (string, error) {
+	upload, err := ReadImageUpload(file)
 	if err != nil {
-		return "", fmt.Errorf("Failed to create upload directory: %v", err)
+		return "", err
 	}
 
-	timestamp := time.Now().Unix()
-	filename := fmt.Sprintf("%d_%s", timestamp, file.Filename)
-
-	filePath := filepath.Join(uploadDir, filename)
-
-	if err := saveFile(file, filePath); err != nil {
+	key, err := uploadKey(file, folder)
+	if err != nil {
 		return "", err
 	}
-	return filePath, nil
+	if _, err := saveFile(store, upload, key); err != nil {
+		return "", err
+	}
+	return key, nil
 }
 
 
-func (u *Utils)UploadItemFile(itemId int, file *multipart.FileHeader, uploadDir string) (string, error) {
+func (u *Utils)UploadItemFile(itemId int, file *multipart.FileHeader, upload *ImageUpload, hash *int64) (*models.Image, error) {
 	log.Printf("Uploading image in item ID %d", itemId)
-	err := os.MkdirAll(uploadDir, os.ModePerm)
-
-
-	if err != nil {
-		return "", fmt.Errorf("Failed to create upload directory: %v", err)
-	}
-	
 
 	if u.imageRepository == nil {
 		log.Print("Item repository not initialized")
-		return "", fmt.Errorf("Item repository not initialized")
+		return nil, fmt.Errorf("Item repository not initialized")
 	}
 
-
-	itemDir := filepath.Join(uploadDir, fmt.Sprintf("item_%d", itemId))
-	err = os.MkdirAll(itemDir, os.ModePerm)
-
-	
+	// A full item is turned away before its file is stored; UploadImage counts
+	// again under a lock, so concurrent uploads cannot slip past the limit
+	count, err := u.imageRepository.CountImages(uint(itemId))
 	if err != nil {
-		return "", fmt.Errorf("Failed to create item directory: %v", err)
+		return nil, err
+	}
+	if count >= models.MaxImagesPerItem {
+		return nil, apperrors.NewPayloadTooLargeWithMessage(fmt.Sprintf("An item can have at most %d images", models.MaxImagesPerItem))
 	}
-
-	timestamp := time.Now().Unix()
-	filename := fmt.Sprintf("%d_%s", timestamp, file.Filename)
-
-	filePath := filepath.Join(itemDir, filename)
 
 	imagePath := fmt.Sprintf("item_%d", itemId)
-
-	err = u.imageRepository.UploadImage(itemId, imagePath, filename)
+	key, err := uploadKey(file, imagePath)
 	if err != nil {
-		log.Print("Failed to update images in item")
-		return "", fmt.Errorf("Failed to upload image")
+		return nil, err
 	}
 
-	if err := saveFile(file, filePath); err != nil {
-		return "", fmt.Errorf("Failed to save file: %v", err)
-	}
-
-	return filePath, nil
-}
-
-
-func saveFile(file *multipart.FileHeader, path string) error {
-	src, err := file.Open()
+	checksum, err := saveFile(u.blobStore, upload, key)
 	if err != nil {
-		return fmt.Errorf("Failed to open file: %v", err)
+		return nil, err
 	}
-	defer src.Close()
 
-	dst, err := os.Create(path)
-	if err != nil {
-		return fmt.Errorf("Failed to create file: %v", err)
+	image := &models.Image{
+		FilePath:    imagePath,
+		FileName:    path.Base(key),
+		ItemId:      uint(itemId),
+		Hash:        hash,
+		ContentType: upload.ContentType,
+		Size:        int64(len(upload.Data)),
+		Checksum:    checksum,
 	}
-	defer dst.Close()
 
-	_, err = dst.ReadFrom(src)
+	err = u.imageRepository.UploadImage(image)
 	if err != nil {
-		return fmt.Errorf("Failed to save file: %v", err)
+		log.Print("Failed to update images in item")
+		if err := u.blobStore.Delete(context.Background(), key); err != nil {
+			log.Printf("Could not remove orphaned blob %s: %v\n", key, err)
+		}
+		return nil, err
 	}
-	return nil
-}
 
+	return image, nil
+}
 
-func ReadImage(folderName, fileName string) ([]byte, error) {
-	filePath := fmt.Sprintf("uploads/%s/%s", folderName, fileName)
-
-
-	file, err := os.Open(filePath)
-
-	if err != nil {
-		log.Printf("Failed to open file: %v", err)
-		return nil, fmt.Errorf("Failed to open file: %v", err)
-	}
-	defer file.Close()
-
-	data, err := ioutil.ReadAll(file)
 
+// uploadKey names a new blob. The random part keeps two uploads of the same
+// file name in the same second from overwriting each other.
+func uploadKey(file *multipart.FileHeader, folder string) (string, error) {
+	id, err := uuid.NewV4()
 	if err != nil {
-		log.Printf("Failed to read file: %v", err)
-		return nil, fmt.Errorf("Failed to read file: %v", err)
+		return "", fmt.Errorf("Failed to name file: %v", err)
 	}
 
-	return data, nil
+	timestamp := time.Now().Unix()
+	return path.Join(folder, fmt.Sprintf("%d_%s_%s", timestamp, id.String()[:8], filepath.Base(file.Filename))), nil
 }
 
 
-func ReadImageByPath(path string) ([]byte, error) {
-	filePath := fmt.Sprintf("uploads/%s", path)
-
-
-	file, err := os.Open(filePath)
-
-	if err != nil {
-		log.Printf("Failed to open file: %v", err)
-		return nil, fmt.Errorf("Failed to open file: %v", err)
-	}
-	defer file.Close()
-
-	data, err := ioutil.ReadAll(file)
-
-	if err != nil {
-		log.Printf("Failed to read file: %v", err)
-		return nil, fmt.Errorf("Failed to read file: %v", err)
+// saveFile stores a checked upload and returns its hex SHA-256 checksum
+func saveFile(store models.IBlobStore, upload *ImageUpload, key string) (string, error) {
+	if err := store.Put(context.Background(), key, bytes.NewReader(upload.Data), int64(len(upload.Data)), upload.ContentType); err != nil {
+		return "", fmt.Errorf("Failed to save file: %v", err)
 	}
 
-	return data, nil
-}
\ No newline at end of file
+	checksum := sha256.Sum256(upload.Data)
+	return hex.EncodeToString(checksum[:]), nil
+}
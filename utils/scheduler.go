@@ -0,0 +1,43 @@
+package utils
+
+import (
+	"log"
+	"time"
+)
+
+
+// RunEvery runs job in the background once every interval, starting one
+// interval from now. Failures and panics are logged and the schedule carries on.
+func RunEvery(name string, interval time.Duration, job func() error) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			runJob(name, job)
+		}
+	}()
+}
+
+
+// RunOnce runs job once in the background, e.g. to warm up data a scheduled
+// job would otherwise only produce after its first interval
+func RunOnce(name string, job func() error) {
+	go runJob(name, job)
+}
+
+
+func runJob(name string, job func() error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Job %s panicked: %v\n", name, r)
+		}
+	}()
+
+	started := time.Now()
+	if err := job(); err != nil {
+		log.Printf("Job %s failed after %v: %v\n", name, time.Since(started), err)
+		return
+	}
+	log.Printf("Job %s finished in %v\n", name, time.Since(started))
+}
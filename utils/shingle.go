@@ -0,0 +1,61 @@
+package utils
+
+import (
+	"hash/fnv"
+	"strings"
+	"unicode"
+)
+
+
+// Shingles breaks text into overlapping runs of size characters after
+// lower-casing it and collapsing punctuation and whitespace, so trivial edits
+// like extra spaces or changed capitalisation do not hide a copy
+func Shingles(text string, size int) map[uint64]struct{} {
+	normalized := []rune(normalizeText(text))
+	shingles := map[uint64]struct{}{}
+
+	if len(normalized) == 0 {
+		return shingles
+	}
+	if len(normalized) <= size {
+		shingles[hashShingle(normalized)] = struct{}{}
+		return shingles
+	}
+
+	for i := 0; i+size <= len(normalized); i++ {
+		shingles[hashShingle(normalized[i:i+size])] = struct{}{}
+	}
+	return shingles
+}
+
+
+// Jaccard returns how much two shingle sets overlap, from 0 for nothing in
+// common to 1 for identical sets
+func Jaccard(a, b map[uint64]struct{}) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+
+	shared := 0
+	for shingle := range a {
+		if _, ok := b[shingle]; ok {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
+
+
+func normalizeText(text string) string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	return strings.Join(words, " ")
+}
+
+
+func hashShingle(shingle []rune) uint64 {
+	hash := fnv.New64a()
+	hash.Write([]byte(string(shingle)))
+	return hash.Sum64()
+}
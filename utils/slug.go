@@ -0,0 +1,16 @@
+package utils
+
+import (
+	"regexp"
+	"strings"
+)
+
+
+var nonSlugChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+
+// Slugify turns a display name such as "Phones & Tablets" into "phones-tablets"
+func Slugify(name string) string {
+	slug := nonSlugChars.ReplaceAllString(strings.ToLower(name), "-")
+	return strings.Trim(slug, "-")
+}